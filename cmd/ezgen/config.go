@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// config 生成配置, 可以来自 yaml/toml 配置文件, 命令行参数优先
+type config struct {
+	DSN     string `yaml:"dsn" toml:"dsn"`
+	Dialect string `yaml:"dialect" toml:"dialect"` // mysql, postgres
+	OutDir  string `yaml:"out_dir" toml:"out_dir"` // dao 目录, model 和 query 生成在其子目录
+
+	TemplateDir string `yaml:"template_dir" toml:"template_dir"` // 自定义模板目录, 缺失的模板使用内置模板
+
+	Tables       []string `yaml:"tables" toml:"tables"` // 为空时生成所有表
+	SkipTables   []string `yaml:"skip_tables" toml:"skip_tables"`
+	SkipPrefixes []string `yaml:"skip_prefixes" toml:"skip_prefixes"`
+	SkipSuffixes []string `yaml:"skip_suffixes" toml:"skip_suffixes"`
+
+	FieldNullable     bool `yaml:"field_nullable" toml:"field_nullable"`
+	FieldSignable     bool `yaml:"field_signable" toml:"field_signable"`
+	FieldCoverable    bool `yaml:"field_coverable" toml:"field_coverable"`
+	FieldWithIndexTag bool `yaml:"field_with_index_tag" toml:"field_with_index_tag"`
+	FieldWithTypeTag  bool `yaml:"field_with_type_tag" toml:"field_with_type_tag"`
+	ForeignKeys       bool `yaml:"foreign_keys" toml:"foreign_keys"` // 根据外键生成关联字段, 仅支持 mysql
+
+	DryRun bool `yaml:"-" toml:"-"` // 只输出 diff, 不写文件
+}
+
+func defaultConfig() *config {
+	return &config{
+		Dialect: "mysql",
+		OutDir:  "internal/dao",
+	}
+}
+
+// loadConfig 读取配置文件, 按扩展名选择 yaml 或 toml
+func loadConfig(path string, c *config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".toml":
+		err = toml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("unsupported config file %s, want .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+// stringList 逗号分隔的命令行参数
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = nil
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// parseFlags 解析命令行参数, 显式指定的参数覆盖配置文件中的值
+func parseFlags(args []string) (*config, error) {
+	var (
+		fs         = flag.NewFlagSet("ezgen", flag.ContinueOnError)
+		configFile = fs.String("config", "", "yaml or toml config file")
+		flagCfg    = &config{}
+	)
+	fs.StringVar(&flagCfg.DSN, "dsn", "", "database dsn")
+	fs.StringVar(&flagCfg.Dialect, "dialect", "mysql", "database dialect: mysql, postgres")
+	fs.StringVar(&flagCfg.OutDir, "out", "internal/dao", "dao output dir, model and query are generated in its sub dirs")
+	fs.StringVar(&flagCfg.TemplateDir, "templates", "", "dir of custom crud.dao.tpl, interface.dao.tpl and dao.tpl")
+	fs.Var((*stringList)(&flagCfg.Tables), "tables", "comma separated tables to generate, default all")
+	fs.Var((*stringList)(&flagCfg.SkipTables), "skip-tables", "comma separated tables to skip")
+	fs.Var((*stringList)(&flagCfg.SkipPrefixes), "skip-prefixes", "comma separated table prefixes to skip")
+	fs.Var((*stringList)(&flagCfg.SkipSuffixes), "skip-suffixes", "comma separated table suffixes to skip")
+	fs.BoolVar(&flagCfg.FieldNullable, "field-nullable", false, "generate pointer fields for nullable columns")
+	fs.BoolVar(&flagCfg.FieldSignable, "field-signable", false, "detect unsigned integer columns")
+	fs.BoolVar(&flagCfg.FieldCoverable, "field-coverable", false, "generate pointer fields for columns with default values")
+	fs.BoolVar(&flagCfg.FieldWithIndexTag, "field-with-index-tag", false, "generate gorm index tags")
+	fs.BoolVar(&flagCfg.FieldWithTypeTag, "field-with-type-tag", false, "generate gorm column type tags")
+	fs.BoolVar(&flagCfg.ForeignKeys, "foreign-keys", false, "generate relations from foreign keys (mysql only)")
+	fs.BoolVar(&flagCfg.DryRun, "dry-run", false, "print a diff of the dao files instead of writing them, exit 1 if anything would change")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	c := defaultConfig()
+	if *configFile != "" {
+		if err := loadConfig(*configFile, c); err != nil {
+			return nil, err
+		}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "dsn":
+			c.DSN = flagCfg.DSN
+		case "dialect":
+			c.Dialect = flagCfg.Dialect
+		case "out":
+			c.OutDir = flagCfg.OutDir
+		case "templates":
+			c.TemplateDir = flagCfg.TemplateDir
+		case "tables":
+			c.Tables = flagCfg.Tables
+		case "skip-tables":
+			c.SkipTables = flagCfg.SkipTables
+		case "skip-prefixes":
+			c.SkipPrefixes = flagCfg.SkipPrefixes
+		case "skip-suffixes":
+			c.SkipSuffixes = flagCfg.SkipSuffixes
+		case "field-nullable":
+			c.FieldNullable = flagCfg.FieldNullable
+		case "field-signable":
+			c.FieldSignable = flagCfg.FieldSignable
+		case "field-coverable":
+			c.FieldCoverable = flagCfg.FieldCoverable
+		case "field-with-index-tag":
+			c.FieldWithIndexTag = flagCfg.FieldWithIndexTag
+		case "field-with-type-tag":
+			c.FieldWithTypeTag = flagCfg.FieldWithTypeTag
+		case "foreign-keys":
+			c.ForeignKeys = flagCfg.ForeignKeys
+		case "dry-run":
+			c.DryRun = flagCfg.DryRun
+		}
+	})
+
+	if c.DSN == "" {
+		return nil, fmt.Errorf("dsn is required")
+	}
+	return c, nil
+}
@@ -0,0 +1,251 @@
+// Command ezgen 连接数据库并一次性生成 model, query, CRUD 与 dao.go
+//
+//	ezgen -dsn "user:pass@tcp(127.0.0.1:3306)/db?parseTime=true" -out internal/dao
+//	ezgen -config ezgen.yaml
+//	ezgen -config ezgen.yaml -dry-run
+//
+// 退出码: 0 成功, 1 dry run 发现生成的代码需要更新, 2 出错
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/ez4bk/gen-ext/ezgen"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gen"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	c, err := parseFlags(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ezgen:", err)
+		os.Exit(2)
+	}
+
+	report := &ezgen.Report{}
+	if err = run(c, report); err != nil {
+		fmt.Fprintln(os.Stderr, "ezgen:", err)
+		os.Exit(2)
+	}
+	printReport(report, c.DryRun)
+	if c.DryRun && report.Changed() {
+		os.Exit(1)
+	}
+}
+
+func run(c *config, report *ezgen.Report) error {
+	db, err := open(c)
+	if err != nil {
+		return err
+	}
+
+	tables, comments, err := listTables(db, c)
+	if err != nil {
+		return err
+	}
+	if len(tables) == 0 {
+		return errors.New("no table to generate")
+	}
+
+	cfg := gen.Config{
+		OutPath:           filepath.Join(c.OutDir, "query"),
+		Mode:              gen.WithDefaultQuery | gen.WithQueryInterface,
+		FieldNullable:     c.FieldNullable,
+		FieldSignable:     c.FieldSignable,
+		FieldCoverable:    c.FieldCoverable,
+		FieldWithIndexTag: c.FieldWithIndexTag,
+		FieldWithTypeTag:  c.FieldWithTypeTag,
+	}
+	g := gen.NewGenerator(cfg)
+	g.UseDB(db)
+
+	var dataMap map[string]func(gorm.ColumnType) (dataType string)
+	switch c.Dialect {
+	case "mysql":
+		dataMap = ezgen.GetDataMapMySQL(&cfg, nil)
+	case "postgres":
+		dataMap = ezgen.GetDataMapPostgreSQL(&cfg, nil)
+	}
+	g.WithDataTypeMap(dataMap)
+
+	models := make([]any, 0, len(tables))
+	modelNames := make([]string, 0, len(tables))
+	for _, table := range tables {
+		opts := ezgen.DefaultModelOpt
+		if c.ForeignKeys && c.Dialect == "mysql" {
+			opts = append(slices.Clip(opts), ezgen.GeneratorForeignKey(g, db, table)...)
+		}
+		m := g.GenerateModel(table, opts...)
+		models = append(models, m)
+		modelNames = append(modelNames, ezgen.ToQueryStructMeta(m).ModelStructName)
+	}
+
+	genOpts := []ezgen.GenOption{ezgen.WithReport(report)}
+	if c.TemplateDir != "" {
+		ts, err := ezgen.LoadTemplateDir(c.TemplateDir)
+		if err != nil {
+			return err
+		}
+		genOpts = append(genOpts, ezgen.WithTemplates(ts))
+	}
+	if c.DryRun {
+		// gorm gen 不支持 dry run, 只检查 ezgen 生成的文件
+		genOpts = append(genOpts, ezgen.WithDryRun())
+	} else {
+		// gorm gen 直接写文件, 通过前后对比得到每个文件的处理结果
+		genDirs := []string{cfg.OutPath, filepath.Join(c.OutDir, "model")}
+		before := snapshot(genDirs...)
+		g.ApplyBasic(models...)
+		g.Execute()
+		diffSnapshot(report, before, snapshot(genDirs...))
+	}
+
+	for i, table := range tables {
+		columnTypes, err := db.Migrator().ColumnTypes(table)
+		if err != nil {
+			return fmt.Errorf("read columns of %s: %w", table, err)
+		}
+		params, err := ezgen.BuildParams(table, modelNames[i], columnTypes, dataMap,
+			ezgen.WithTableComment(comments[table]),
+			ezgen.WithRelations(ezgen.RelationNames(ezgen.ToQueryStructMeta(models[i]))...))
+		if err != nil {
+			return err
+		}
+		if err = ezgen.Generate(params, c.OutDir, table, genOpts...); err != nil {
+			return fmt.Errorf("generate %s: %w", table, err)
+		}
+	}
+
+	return ezgen.GenerateDao(modelNames, filepath.Join(c.OutDir, "dao.go"), genOpts...)
+}
+
+func open(c *config) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch c.Dialect {
+	case "mysql":
+		dialector = mysql.Open(c.DSN)
+	case "postgres":
+		dialector = postgres.Open(c.DSN)
+	default:
+		return nil, fmt.Errorf("unsupported dialect %q", c.Dialect)
+	}
+	return gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Warn)})
+}
+
+// listTables 返回需要生成的表和每张表的注释, 注释只查询一次
+func listTables(db *gorm.DB, c *config) ([]string, map[string]string, error) {
+	all, err := db.Migrator().GetTables()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filter := &ezgen.TableFilter{
+		SkipTables:   c.SkipTables,
+		SkipPrefixes: c.SkipPrefixes,
+		SkipSuffixes: c.SkipSuffixes,
+	}
+	tables := make([]string, 0, len(all))
+	comments := make(map[string]string, len(all))
+	for _, table := range all {
+		if len(c.Tables) > 0 && !slices.Contains(c.Tables, table) {
+			continue
+		}
+		if filter.ShouldSkip(table) {
+			continue
+		}
+		comment := tableComment(db, table)
+		a, err := ezgen.ParseTableAnnotation(comment)
+		if err != nil {
+			return nil, nil, fmt.Errorf("table %s: %w", table, err)
+		}
+		if a.Skip {
+			continue
+		}
+		tables = append(tables, table)
+		comments[table] = comment
+	}
+	return tables, comments, nil
+}
+
+// tableComment 返回表注释, 不支持读取表注释的数据库返回空
+func tableComment(db *gorm.DB, table string) string {
+	t, err := db.Migrator().TableType(table)
+	if err != nil {
+		return ""
+	}
+	comment, _ := t.Comment()
+	return comment
+}
+
+type fileState struct {
+	sum     [sha256.Size]byte
+	modTime time.Time
+}
+
+// snapshot 记录目录下每个文件的内容摘要和修改时间
+func snapshot(dirs ...string) map[string]fileState {
+	states := make(map[string]fileState)
+	for _, dir := range dirs {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil || e.IsDir() {
+				continue
+			}
+			name := filepath.Join(dir, e.Name())
+			if data, err := os.ReadFile(name); err == nil {
+				states[name] = fileState{sum: sha256.Sum256(data), modTime: info.ModTime()}
+			}
+		}
+	}
+	return states
+}
+
+// diffSnapshot 汇总 gorm gen 写过的文件, 未被写过的旧文件不计入
+func diffSnapshot(report *ezgen.Report, before, after map[string]fileState) {
+	names := make([]string, 0, len(after))
+	for name := range after {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		old, ok := before[name]
+		switch {
+		case !ok:
+			report.Add(name, ezgen.FileCreated)
+		case old.sum != after[name].sum:
+			report.Add(name, ezgen.FileOverwritten)
+		case !old.modTime.Equal(after[name].modTime):
+			report.Add(name, ezgen.FileUnchanged)
+		}
+	}
+}
+
+func printReport(report *ezgen.Report, dryRun bool) {
+	counts := make(map[ezgen.FileAction]int)
+	for _, f := range report.Files() {
+		counts[f.Action]++
+		fmt.Printf("%-12s %s\n", f.Action, f.Path)
+	}
+	if dryRun {
+		for _, f := range report.Files() {
+			fmt.Print(f.Diff)
+		}
+		fmt.Print("dry run, model and query files are not checked: ")
+	}
+	fmt.Printf("%d created, %d overwritten, %d unchanged, %d skipped\n",
+		counts[ezgen.FileCreated], counts[ezgen.FileOverwritten], counts[ezgen.FileUnchanged], counts[ezgen.FileSkipped])
+}
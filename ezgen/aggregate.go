@@ -0,0 +1,72 @@
+package ezgen
+
+import (
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GroupCount CountBy 的一组结果
+type GroupCount struct {
+	Value any   // 分组列的值, 类型与模型字段相同, 指针字段为解引用后的值, NULL 为 nil
+	Count int64 // 该值的行数
+}
+
+// Exists 返回 db 的查询条件是否匹配任意行, 最多只读取一行
+func Exists(db *gorm.DB) (bool, error) {
+	var found []int
+	if err := db.Select("1").Limit(1).Find(&found).Error; err != nil {
+		return false, err
+	}
+	return len(found) > 0, nil
+}
+
+// CountBy 按模型 T 的 column 列分组统计 db 匹配的行数, 结果按分组值升序(NULL 在前).
+// column 不是 T 的列时返回 ErrInvalidField. 结果不使用查询缓存
+func CountBy[T any](db *gorm.DB, column string) ([]GroupCount, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	field := stmt.Schema.LookUpField(column)
+	if field == nil || field.DBName == "" {
+		return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidField, column)
+	}
+
+	col := clause.Column{Name: field.DBName}
+	rows, err := db.Select("?, COUNT(*)", col).
+		Clauses(clause.GroupBy{Columns: []clause.Column{col}}).
+		Order(clause.OrderByColumn{Column: col}).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []GroupCount
+	for rows.Next() {
+		value := reflect.New(reflect.PointerTo(field.FieldType))
+		var count int64
+		if err := rows.Scan(value.Interface(), &count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, GroupCount{Value: groupValue(value.Elem()), Count: count})
+	}
+	return counts, rows.Err()
+}
+
+// groupValue 解引用 v 直到非指针, nil 指针返回 nil
+func groupValue(v reflect.Value) any {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
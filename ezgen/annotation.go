@@ -0,0 +1,100 @@
+package ezgen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTableSkipped 表注释中有 @ezgen:skip, BuildParams 不生成该表
+var ErrTableSkipped = errors.New("ezgen: table skipped by @ezgen:skip")
+
+// AnnotationPrefix 注释中 ezgen 指令的前缀, 如 "@ezgen:sort=desc @ezgen:filter=in,range"
+const AnnotationPrefix = "@ezgen:"
+
+// Annotation 列或表注释中 ezgen 指令的解析结果. 指令之间以空白分隔, 注释中的其他文字会被忽略
+//
+//	@ezgen:sort=asc|desc  列: 作为 List 的排序列; 表: 主键排序的方向
+//	@ezgen:filter=in,eq   列: List 的过滤方式, 见 FilterKind
+//	@ezgen:nofilter       列: 不生成 List 过滤条件, 也不能用于 OrderBy 排序
+//	@ezgen:sensitive      列: 敏感数据, 同 nofilter, 并记录在 GenParams.SensitiveFields 中
+//	@ezgen:skip           表: 不生成该表
+type Annotation struct {
+	Sort      string       // asc, desc, 未指定时为空
+	Filters   []FilterKind // 未指定时为 nil
+	NoFilter  bool
+	Sensitive bool
+	Skip      bool
+}
+
+// ParseAnnotation 解析注释中的 ezgen 指令, 未知的指令, 缺少或多余的值以及重复的指令都会返回错误
+func ParseAnnotation(comment string) (*Annotation, error) {
+	a := &Annotation{}
+	seen := make(map[string]bool)
+	for _, field := range strings.Fields(comment) {
+		directive, ok := strings.CutPrefix(field, AnnotationPrefix)
+		if !ok {
+			continue
+		}
+		name, value, hasValue := strings.Cut(directive, "=")
+		if seen[name] {
+			return nil, fmt.Errorf("duplicated directive %s%s", AnnotationPrefix, name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "sort":
+			if value != "asc" && value != "desc" {
+				return nil, fmt.Errorf("directive %s: want sort=asc or sort=desc", field)
+			}
+			a.Sort = value
+		case "filter":
+			if value == "" {
+				return nil, fmt.Errorf("directive %s: want filter=kind[,kind...]", field)
+			}
+			kinds, err := ParseFilterKinds(value)
+			if err != nil {
+				return nil, fmt.Errorf("directive %s: %w", field, err)
+			}
+			a.Filters = kinds
+		case "nofilter", "sensitive", "skip":
+			if hasValue {
+				return nil, fmt.Errorf("directive %s: %s takes no value", field, name)
+			}
+			a.NoFilter = a.NoFilter || name == "nofilter"
+			a.Sensitive = a.Sensitive || name == "sensitive"
+			a.Skip = a.Skip || name == "skip"
+		default:
+			return nil, fmt.Errorf("unknown directive %s, want sort, filter, nofilter, sensitive or skip", field)
+		}
+	}
+	return a, nil
+}
+
+// ParseTableAnnotation 解析表注释中的 ezgen 指令, 表注释只支持 sort 和 skip
+func ParseTableAnnotation(comment string) (*Annotation, error) {
+	a, err := ParseAnnotation(comment)
+	if err != nil {
+		return nil, err
+	}
+	if a.Filters != nil || a.NoFilter || a.Sensitive {
+		return nil, fmt.Errorf("only %ssort and %sskip are allowed in table comments", AnnotationPrefix, AnnotationPrefix)
+	}
+	return a, nil
+}
+
+// ParseColumnAnnotation 解析列注释中的 ezgen 指令, 列注释不支持 skip
+func ParseColumnAnnotation(comment string) (*Annotation, error) {
+	a, err := ParseAnnotation(comment)
+	if err != nil {
+		return nil, err
+	}
+	if a.Skip {
+		return nil, fmt.Errorf("%sskip is only allowed in table comments, use %snofilter to generate no List filter",
+			AnnotationPrefix, AnnotationPrefix)
+	}
+	if a.Filters != nil && (a.NoFilter || a.Sensitive) {
+		return nil, errors.New("filter can not be combined with nofilter or sensitive")
+	}
+	return a, nil
+}
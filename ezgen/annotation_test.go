@@ -0,0 +1,106 @@
+package ezgen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAnnotation(t *testing.T) {
+	tests := []struct {
+		comment string
+		want    *Annotation
+		err     string
+	}{
+		{comment: "", want: &Annotation{}},
+		{comment: "user name, asc", want: &Annotation{}},
+		{comment: "@ezgen:sort=desc", want: &Annotation{Sort: "desc"}},
+		{comment: "created time @ezgen:sort=asc", want: &Annotation{Sort: "asc"}},
+		{comment: "@ezgen:filter=in,range", want: &Annotation{Filters: []FilterKind{FilterIn, FilterRange}}},
+		{comment: "@ezgen:filter=in, @ezgen:nofilter", err: "unknown filter"},
+		{comment: "@ezgen:nofilter @ezgen:sensitive", want: &Annotation{NoFilter: true, Sensitive: true}},
+		{comment: "@ezgen:skip", want: &Annotation{Skip: true}},
+		{comment: "@ezgen:sort", err: "want sort=asc or sort=desc"},
+		{comment: "@ezgen:sort=up", err: "want sort=asc or sort=desc"},
+		{comment: "@ezgen:filter=", err: "want filter=kind"},
+		{comment: "@ezgen:filter=between", err: "unknown filter"},
+		{comment: "@ezgen:skip=true", err: "takes no value"},
+		{comment: "@ezgen:nofilter=true", err: "takes no value"},
+		{comment: "@ezgen:sort=asc @ezgen:sort=desc", err: "duplicated directive"},
+		{comment: "@ezgen:hidden", err: "unknown directive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.comment, func(t *testing.T) {
+			got, err := ParseAnnotation(tt.comment)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("ParseAnnotation(%q) error = %v, want %q", tt.comment, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAnnotation(%q) error = %v", tt.comment, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAnnotation(%q) = %+v, want %+v", tt.comment, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTableAnnotation(t *testing.T) {
+	tests := []struct {
+		comment string
+		want    *Annotation
+		err     bool
+	}{
+		{comment: "orders @ezgen:sort=asc", want: &Annotation{Sort: "asc"}},
+		{comment: "@ezgen:skip", want: &Annotation{Skip: true}},
+		{comment: "@ezgen:filter=eq", err: true},
+		{comment: "@ezgen:sensitive", err: true},
+		{comment: "@ezgen:nofilter", err: true},
+		{comment: "@ezgen:sort=sideways", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.comment, func(t *testing.T) {
+			got, err := ParseTableAnnotation(tt.comment)
+			if (err != nil) != tt.err {
+				t.Fatalf("ParseTableAnnotation(%q) error = %v, want error %v", tt.comment, err, tt.err)
+			}
+			if !tt.err && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTableAnnotation(%q) = %+v, want %+v", tt.comment, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseColumnAnnotation(t *testing.T) {
+	tests := []struct {
+		comment string
+		want    *Annotation
+		err     string
+	}{
+		{comment: "@ezgen:nofilter", want: &Annotation{NoFilter: true}},
+		{comment: "@ezgen:sort=desc @ezgen:sensitive", want: &Annotation{Sort: "desc", Sensitive: true}},
+		{comment: "@ezgen:skip", err: "use @ezgen:nofilter"},
+		{comment: "@ezgen:filter=eq @ezgen:nofilter", err: "can not be combined"},
+		{comment: "@ezgen:filter=eq @ezgen:sensitive", err: "can not be combined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.comment, func(t *testing.T) {
+			got, err := ParseColumnAnnotation(tt.comment)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("ParseColumnAnnotation(%q) error = %v, want %q", tt.comment, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseColumnAnnotation(%q) error = %v", tt.comment, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseColumnAnnotation(%q) = %+v, want %+v", tt.comment, got, tt.want)
+			}
+		})
+	}
+}
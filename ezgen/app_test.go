@@ -0,0 +1,140 @@
+package ezgen
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gen"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// appDDL creates the tables of the generated test app
+var appDDL = []string{
+	`create table users (
+		id integer not null primary key autoincrement,
+		name varchar(64) not null,
+		email varchar(64) not null unique,
+		age integer,
+		created_at datetime not null,
+		version bigint,
+		deleted_at datetime
+	)`,
+}
+
+// genApp generates a dao package under testdata, which ./... does not match: the tables of appDDL are created in
+// SQLite, gorm gen writes their model and query packages and Generate and GenerateDao the dao files and mocks.
+// It returns the directory of the app, removed when the test ends
+func genApp(t *testing.T) string {
+	t.Helper()
+	if err := os.MkdirAll("testdata", 0o777); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := os.MkdirTemp("testdata", "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "app.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ddl := range appDDL {
+		if err = db.Exec(ddl).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	var tables []string
+	err = db.Raw("select name from sqlite_master where type = 'table' and name not like 'sqlite_%' order by name").
+		Scan(&tables).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	daoDir := filepath.Join(dir, "dao")
+	cfg := gen.Config{
+		OutPath:       filepath.Join(daoDir, "query"),
+		Mode:          gen.WithDefaultQuery | gen.WithQueryInterface,
+		FieldNullable: true,
+	}
+	g := gen.NewGenerator(cfg)
+	g.UseDB(db)
+	dataMap := GetDataMapMySQL(&cfg, nil)
+	g.WithDataTypeMap(dataMap)
+	models := make([]any, 0, len(tables))
+	modelNames := make([]string, 0, len(tables))
+	for _, table := range tables {
+		// gorm.io/plugin/optimisticlock is not a dependency of this module
+		m := g.GenerateModel(table, gen.FieldType("version", "sql.NullInt64"))
+		models = append(models, m)
+		modelNames = append(modelNames, ToQueryStructMeta(m).ModelStructName)
+	}
+	g.ApplyBasic(models...)
+	g.Execute()
+
+	for i, table := range tables {
+		columnTypes, err := db.Migrator().ColumnTypes(table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		params, err := BuildParams(table, modelNames[i], columnTypes, dataMap,
+			WithRelations(RelationNames(ToQueryStructMeta(models[i]))...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = Generate(params, daoDir, table); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = GenerateDao(modelNames, filepath.Join(daoDir, "dao.go")); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// goCmd runs the go command in the module root
+func goCmd(t *testing.T, args ...string) {
+	t.Helper()
+	cmd := exec.Command("go", args...)
+	cmd.Dir = ".."
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+// TestGeneratedDao compiles and vets the generated packages, then runs the tests of testdata/daotest against the
+// generated dao and its fake on SQLite
+func TestGeneratedDao(t *testing.T) {
+	if testing.Short() {
+		t.Skip("generates and builds a dao package")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	dir := genApp(t)
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "daotest", "*_test.go.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fixture := range fixtures {
+		src, err := os.ReadFile(fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the fixtures import the generated packages from testdata/APP
+		src = bytes.ReplaceAll(src, []byte("/testdata/APP/"), []byte("/testdata/"+filepath.Base(dir)+"/"))
+		name := strings.TrimSuffix(filepath.Base(fixture), ".txt")
+		if err = os.WriteFile(filepath.Join(dir, "dao", name), src, 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pkg := "./" + filepath.ToSlash(filepath.Join("ezgen", dir))
+	goCmd(t, "vet", pkg+"/...")
+	goCmd(t, "test", "-count=1", pkg+"/dao")
+}
@@ -0,0 +1,67 @@
+package ezgen
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultBatchSize Each 的 batchSize 不大于 0 时和 Iter 每批查询的行数
+const DefaultBatchSize = 500
+
+// errStopIter Iter 的调用方提前结束循环
+var errStopIter = errors.New("ezgen: stop iteration")
+
+// EachBatch 按 primaryField 升序分批(keyset)查询, 每批最多 size 行, 对每批调用 fn.
+// query 每批调用一次, 返回带有过滤条件的新查询, key 返回行的主键.
+// fn 返回错误或 ctx 取消时停止并返回该错误. 每批是独立的查询, 遍历期间写入的行可能不会被读到
+func EachBatch[T any](ctx context.Context, query func() *gorm.DB, primaryField string, size int, key func(row *T) any, fn func(batch []*T) error) error {
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	column := clause.Column{Table: clause.CurrentTable, Name: primaryField}
+	var last any
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tx := query().Order(clause.OrderByColumn{Column: column}).Limit(size)
+		if last != nil {
+			tx = tx.Clauses(clause.Gt{Column: column, Value: last})
+		}
+		var batch []*T
+		if err := tx.Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < size {
+			return nil
+		}
+		last = key(batch[len(batch)-1])
+	}
+}
+
+// Iter 把分批遍历 each 转成逐行的迭代器, each 出错时最后产出 (nil, err)
+func Iter[T any](each func(fn func(batch []*T) error) error) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		err := each(func(batch []*T) error {
+			for _, row := range batch {
+				if !yield(row, nil) {
+					return errStopIter
+				}
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStopIter) {
+			yield(nil, err)
+		}
+	}
+}
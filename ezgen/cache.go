@@ -0,0 +1,309 @@
+package ezgen
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/schema"
+)
+
+// CacheMode 查询的缓存模式, 通过 context 传递给 CachePlugin
+type CacheMode int
+
+const (
+	ModeNone   CacheMode = iota // 不使用缓存
+	ModeCached                  // 优先读缓存, 未命中时查询数据库并写入缓存
+	ModeWarm                    // 总是查询数据库, 并用结果刷新缓存
+)
+
+type modeKey struct{}
+
+// ModeKey context 中 CacheMode 的 key
+var ModeKey = modeKey{}
+
+// WithCacheMode 返回携带缓存模式的 context, ModeNone 时原样返回
+func WithCacheMode(ctx context.Context, mode CacheMode) context.Context {
+	if mode == ModeNone {
+		return ctx
+	}
+	return context.WithValue(ctx, ModeKey, mode)
+}
+
+// CacheModeFrom 取出 context 中的缓存模式
+func CacheModeFrom(ctx context.Context) CacheMode {
+	if ctx == nil {
+		return ModeNone
+	}
+	mode, _ := ctx.Value(ModeKey).(CacheMode)
+	return mode
+}
+
+// Cache CachePlugin 使用的缓存后端
+type Cache interface {
+	// Get 读取 key, 不存在时 ok 为 false
+	Get(ctx context.Context, key string) (val []byte, ok bool, err error)
+	// Set 写入 key, ttl <= 0 时使用后端的默认过期时间
+	Set(ctx context.Context, key string, val []byte, ttl time.Duration) error
+}
+
+const cachePluginName = "ezgen:cache"
+
+// CachePlugin 按 context 中的 CacheMode 缓存查询结果的 gorm 插件.
+// 缓存 key 由表名, 表的版本和完整 SQL 组成, InvalidateCache 通过更换表的版本使该表的缓存全部失效.
+// 事务中的查询和加锁查询不走缓存
+type CachePlugin struct {
+	cache  Cache
+	ttl    time.Duration
+	prefix string
+}
+
+// NewCachePlugin 创建缓存插件, 查询结果缓存 ttl, 通过 db.Use 注册
+func NewCachePlugin(c Cache, ttl time.Duration) *CachePlugin {
+	return &CachePlugin{cache: c, ttl: ttl, prefix: "ezgen:"}
+}
+
+func (p *CachePlugin) Name() string {
+	return cachePluginName
+}
+
+func (p *CachePlugin) Initialize(db *gorm.DB) error {
+	return db.Callback().Query().Replace("gorm:query", p.query)
+}
+
+type cacheEntry struct {
+	Rows int64           `json:"r"`
+	Data json.RawMessage `json:"d"`
+}
+
+func (p *CachePlugin) query(db *gorm.DB) {
+	stmt := db.Statement
+	mode := CacheModeFrom(stmt.Context)
+	_, inTx := stmt.ConnPool.(gorm.TxCommitter)
+	_, locking := stmt.Clauses["FOR"]
+	if mode == ModeNone || inTx || locking || db.DryRun || db.Error != nil || stmt.Dest == nil {
+		callbacks.Query(db)
+		return
+	}
+
+	callbacks.BuildQuerySQL(db)
+	if db.Error != nil {
+		return
+	}
+	key, err := p.key(stmt.Context, tenantName(db), stmt.Table, db.Dialector.Explain(stmt.SQL.String(), stmt.Vars...))
+	if err != nil {
+		db.Logger.Error(stmt.Context, "ezgen cache: %v", err)
+		callbacks.Query(db)
+		return
+	}
+
+	if mode == ModeCached {
+		val, ok, err := p.cache.Get(stmt.Context, key)
+		if err != nil {
+			db.Logger.Error(stmt.Context, "ezgen cache: get %s: %v", key, err)
+		}
+		var entry cacheEntry
+		if ok && json.Unmarshal(val, &entry) == nil && json.Unmarshal(entry.Data, stmt.Dest) == nil {
+			db.RowsAffected = entry.Rows
+			if db.RowsAffected == 0 && stmt.RaiseErrorOnNotFound {
+				db.AddError(gorm.ErrRecordNotFound)
+			}
+			return
+		}
+	}
+
+	callbacks.Query(db)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		return
+	}
+	data, err := json.Marshal(stmt.Dest)
+	if err == nil {
+		data, err = json.Marshal(cacheEntry{Rows: db.RowsAffected, Data: data})
+	}
+	if err == nil {
+		err = p.cache.Set(stmt.Context, key, data, p.ttl)
+	}
+	if err != nil {
+		db.Logger.Error(stmt.Context, "ezgen cache: set %s: %v", key, err)
+	}
+}
+
+// key 返回 sql 在 table 当前版本下的缓存 key, 不同租户的同名表互不影响
+func (p *CachePlugin) key(ctx context.Context, tenant, table, sql string) (string, error) {
+	version, ok, err := p.cache.Get(ctx, p.versionKey(tenant, table))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		// 版本丢失(过期或被淘汰)时不能从头计数, 否则会读到更早版本的缓存
+		if version, err = p.invalidate(ctx, tenant, table); err != nil {
+			return "", err
+		}
+	}
+	sum := sha256.Sum256([]byte(sql))
+	return p.tablePrefix(tenant, table) + string(version) + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+func (p *CachePlugin) versionKey(tenant, table string) string {
+	return p.tablePrefix(tenant, table) + "version"
+}
+
+func (p *CachePlugin) tablePrefix(tenant, table string) string {
+	if tenant != "" {
+		return p.prefix + tenant + ":" + table + ":"
+	}
+	return p.prefix + table + ":"
+}
+
+// invalidate 为 table 换上新的版本
+func (p *CachePlugin) invalidate(ctx context.Context, tenant, table string) ([]byte, error) {
+	version := []byte(strconv.FormatInt(time.Now().UnixNano(), 36))
+	return version, p.cache.Set(ctx, p.versionKey(tenant, table), version, 0)
+}
+
+// InvalidateCache 使 tables 的查询缓存失效, db 未注册 CachePlugin 时不做任何处理.
+// ctx 在 Transaction 开启的事务中, 或来自 TrackWrites 时, 事务提交后会再失效一次,
+// 避免提交前的并发查询把旧数据写回缓存. db 是 Tenants.Route 返回的时使用 ctx 中租户的数据库
+func InvalidateCache(ctx context.Context, db *gorm.DB, tables ...string) {
+	db = tenantConn(ctx, db)
+	if _, ok := db.Config.Plugins[cachePluginName].(*CachePlugin); !ok {
+		return
+	}
+	if pending, ok := ctx.Value(txTablesKey{}).(*txTables); ok {
+		pending.add(tables...)
+	}
+	invalidateTables(ctx, db, tables)
+}
+
+// invalidateTables 为 tables 换上新的版本
+func invalidateTables(ctx context.Context, db *gorm.DB, tables []string) {
+	p, ok := db.Config.Plugins[cachePluginName].(*CachePlugin)
+	if !ok {
+		return
+	}
+	for _, table := range tables {
+		if _, err := p.invalidate(ctx, tenantName(db), table); err != nil {
+			db.Logger.Error(ctx, "ezgen cache: invalidate %s: %v", table, err)
+		}
+	}
+}
+
+// ModelTables 返回 table 和 model 的关联(包括嵌套的关联和多对多的连接表)所在的表.
+// 级联删除和保存关联会写这些表, 写 model 后它们的缓存都需要失效
+func ModelTables(db *gorm.DB, table string, model any) []string {
+	tables := []string{table}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return tables
+	}
+	var walk func(s *schema.Schema)
+	walk = func(s *schema.Schema) {
+		for _, rel := range s.Relationships.Relations {
+			if rel.JoinTable != nil && !slices.Contains(tables, rel.JoinTable.Table) {
+				tables = append(tables, rel.JoinTable.Table)
+			}
+			if !slices.Contains(tables, rel.FieldSchema.Table) {
+				tables = append(tables, rel.FieldSchema.Table)
+				walk(rel.FieldSchema)
+			}
+		}
+	}
+	walk(stmt.Schema)
+	slices.Sort(tables[1:])
+	return tables
+}
+
+// LRUCache 进程内的 LRU 缓存
+type LRUCache struct {
+	mu      sync.Mutex
+	size    int
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key      string
+	val      []byte
+	expireAt time.Time // 零值表示不过期
+}
+
+// NewLRUCache 创建最多保存 size 个 key 的 LRU 缓存
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{size: max(size, 1), ll: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *LRUCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := elem.Value.(*lruEntry)
+	if !entry.expireAt.IsZero() && time.Now().After(entry.expireAt) {
+		c.ll.Remove(elem)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	c.ll.MoveToFront(elem)
+	return entry.val, true, nil
+}
+
+func (c *LRUCache) Set(_ context.Context, key string, val []byte, ttl time.Duration) error {
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = &lruEntry{key: key, val: val, expireAt: expireAt}
+		c.ll.MoveToFront(elem)
+		return nil
+	}
+	c.entries[key] = c.ll.PushFront(&lruEntry{key: key, val: val, expireAt: expireAt})
+	for c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+// GoZeroCache 适配 go-zero 的 cache.Cache(redis)
+type GoZeroCache struct {
+	c cache.Cache
+}
+
+// NewGoZeroCache 使用 go-zero 的 cache.Cache 作为缓存后端
+func NewGoZeroCache(c cache.Cache) *GoZeroCache {
+	return &GoZeroCache{c: c}
+}
+
+func (c *GoZeroCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	var val string
+	if err := c.c.GetCtx(ctx, key, &val); err != nil {
+		if c.c.IsNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return []byte(val), true, nil
+}
+
+func (c *GoZeroCache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return c.c.SetCtx(ctx, key, string(val))
+	}
+	return c.c.SetWithExpireCtx(ctx, key, string(val), ttl)
+}
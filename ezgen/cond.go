@@ -1,10 +1,14 @@
 package ezgen
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 
 	"gorm.io/gen"
+	"gorm.io/gen/field"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func WithDeletedList(withDeleted []bool) func(db *gorm.DB) *gorm.DB {
@@ -29,13 +33,56 @@ func WithDeleted(withDeleted bool) func(db *gorm.DB) *gorm.DB {
 
 func Paginate(p Pager) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
-		if p == nil || reflect.ValueOf(p).IsNil() || p.GetPageSize() <= 0 || p.GetPageIndex() <= 0 {
+		if isNil(p) || p.GetPageSize() <= 0 || p.GetPageIndex() <= 0 {
 			return db
 		}
 		return db.Offset(int((p.GetPageIndex() - 1) * p.GetPageSize())).Limit(int(p.GetPageSize()))
 	}
 }
 
+// PaginateCursor keyset 分页, 调用方需要先按 sortField 排序, 主键作为第二排序字段由此追加 (ORDER BY 中已有主键时不追加, 如 OrderScope)
+func PaginateCursor(p CursorPager, sortField, primaryField string, desc bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if isNil(p) || p.GetPageSize() <= 0 {
+			return db
+		}
+		if sortField != primaryField && !orderedBy(db, primaryField) {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: primaryField}, Desc: desc})
+		}
+		db = db.Limit(int(p.GetPageSize()))
+		if p.GetCursor() == "" {
+			return db
+		}
+
+		c, err := DecodeCursor(p.GetCursor())
+		if err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		if sortField == primaryField {
+			return db.Where(fmt.Sprintf("%s %s ?", primaryField, op), c.PK)
+		}
+		return db.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", sortField, op, sortField, primaryField, op),
+			c.Sort, c.Sort, c.PK)
+	}
+}
+
+// isNil 判断 v 为 nil 或 nil 指针, 值类型的 Pager 和 CursorPager 不为 nil
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	}
+	return false
+}
+
 func Cond(cond bool, query any, args ...any) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		if cond {
@@ -45,6 +92,28 @@ func Cond(cond bool, query any, args ...any) func(db *gorm.DB) *gorm.DB {
 	}
 }
 
+// CondLike 在 cond 为 true 时添加 column like pattern escape '\' 条件, pattern 中的用户输入需经过 EscapeLike.
+// MySQL 的字符串字面量会转义反斜杠, 所以在 MySQL 上写作 escape '\\'
+func CondLike(cond bool, column, pattern string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if !cond {
+			return db
+		}
+		escape := `'\'`
+		if db.Dialector.Name() == "mysql" {
+			escape = `'\\'`
+		}
+		return db.Where(column+" like ? escape "+escape, pattern)
+	}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike 转义 s 中的 \, % 和 _, 使其在 CondLike 的模式中按字面匹配
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
 func Nullable(cond bool, query any, f func() any) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		if cond {
@@ -76,13 +145,48 @@ func WithDeletedGen(withDeleted bool) func(db gen.Dao) gen.Dao {
 
 func PaginateGen(p Pager) func(db gen.Dao) gen.Dao {
 	return func(db gen.Dao) gen.Dao {
-		if p == nil || reflect.ValueOf(p).IsNil() || p.GetPageSize() <= 0 || p.GetPageIndex() <= 0 {
+		if isNil(p) || p.GetPageSize() <= 0 || p.GetPageIndex() <= 0 {
 			return db
 		}
 		return db.Offset(int((p.GetPageIndex() - 1) * p.GetPageSize())).Limit(int(p.GetPageSize()))
 	}
 }
 
+func PaginateCursorGen(p CursorPager, sortField, primaryField field.OrderExpr, desc bool) func(db gen.Dao) gen.Dao {
+	return func(db gen.Dao) gen.Dao {
+		if isNil(p) || p.GetPageSize() <= 0 {
+			return db
+		}
+		samePK := sortField.ColumnName() == primaryField.ColumnName()
+		if !samePK {
+			if desc {
+				db = db.Order(primaryField.Desc())
+			} else {
+				db = db.Order(primaryField)
+			}
+		}
+		db = db.Limit(int(p.GetPageSize()))
+		if p.GetCursor() == "" {
+			return db
+		}
+
+		c, err := DecodeCursor(p.GetCursor())
+		if err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		if samePK {
+			return db.Where(field.NewUnsafeFieldRaw("? "+op+" ?", primaryField, c.PK))
+		}
+		return db.Where(field.NewUnsafeFieldRaw("(? "+op+" ? OR (? = ? AND ? "+op+" ?))",
+			sortField, c.Sort, sortField, c.Sort, primaryField, c.PK))
+	}
+}
+
 func CondGen(cond bool, conds ...gen.Condition) func(db gen.Dao) gen.Dao {
 	return func(db gen.Dao) gen.Dao {
 		if cond {
@@ -2,15 +2,16 @@
 // Code generated by ezgen. DO NOT EDIT.
 // Code generated by ezgen. DO NOT EDIT.
 
-package dao
+package {{.DaoPkgName}}
 
 import (
 	"context"
+	"fmt"
+	"iter"
 	"reflect"
 
-	"{{.ModelPackage}}/internal/constants"
-	"{{.ModelPackage}}/internal/dao/model"
-	"{{.ModelPackage}}/internal/dao/query"
+	"{{.ModelPkgPath}}"
+	"{{.QueryPkgPath}}"
 
 	"github.com/ez4bk/gen-ext/ezgen"
 
@@ -19,36 +20,160 @@ import (
 	{{range .ImportPkgPaths}}{{.}} ` + "\n" + `{{end}}
 )
 
+// {{.CrudInterface}} contains the generated methods, it is embedded by {{.InterfaceName}}
+type {{.CrudInterface}} interface {
+	Add(ctx context.Context, data ...*model.{{.ModelName}}) (err error)
+	// Upsert inserts data, rows conflicting on conflictColumns get updateColumns updated
+	Upsert(ctx context.Context, conflictColumns, updateColumns []string, data ...*model.{{.ModelName}}) (err error)
+	Get(ctx context.Context, id {{.PKType}}, opts ...GetOption) (result *model.{{.ModelName}}, err error)
+	// List returns the specified models from database by params
+	List(ctx context.Context, params *List{{.ModelName}}Params) (list []*model.{{.ModelName}}, total int64, err error)
+	// Count returns the number of rows matching the filters of params, pagination, sorting and fields are ignored
+	Count(ctx context.Context, params *List{{.ModelName}}Params) (count int64, err error)
+	// Exists reports whether any row matches the filters of params
+	Exists(ctx context.Context, params *List{{.ModelName}}Params) (exists bool, err error)
+	// CountBy counts the rows matching the filters of params per value of column, a column in {{.ModelName}}SortColumns
+	CountBy(ctx context.Context, params *List{{.ModelName}}Params, column string) (counts []ezgen.GroupCount, err error)
+	// Each calls fn with the rows matching the filters of params in batches of batchSize, in primary key order.
+	// Pagination, sorting and Count are ignored, and it stops at the first error from fn or when ctx is done
+	Each(ctx context.Context, params *List{{.ModelName}}Params, batchSize int, fn func(batch []*model.{{.ModelName}}) error) (err error)
+	// Iter iterates over the rows of Each in batches of ezgen.DefaultBatchSize, an error is yielded last
+	Iter(ctx context.Context, params *List{{.ModelName}}Params) iter.Seq2[*model.{{.ModelName}}, error]
+	Update(ctx context.Context, data *model.{{.ModelName}}) (err error)
+	// UpdateColumns updates the given columns of the row by id, zero values included
+	UpdateColumns(ctx context.Context, id {{.PKType}}, columns map[string]any) (rows int64, err error)
+	// UpdateSelect updates the selected columns of data by its primary key, zero values included
+	UpdateSelect(ctx context.Context, data *model.{{.ModelName}}, columns ...string) (rows int64, err error)
+	// Delete soft deletes data
+	Delete(ctx context.Context, id {{.PKType}}) (err error)
+	// Destroy hard deletes data
+	Destroy(ctx context.Context, id {{.PKType}}) (err error)
+	// DeleteByIDs soft deletes the rows by id with their associations, like Delete
+	DeleteByIDs(ctx context.Context, ids ...{{.PKType}}) (rows int64, err error)
+	// DestroyByIDs hard deletes the rows by id with their associations, like Destroy
+	DestroyByIDs(ctx context.Context, ids ...{{.PKType}}) (rows int64, err error)
+	// DeleteWhere soft deletes the rows matching the filters of params, associations are kept.
+	// It fails with ezgen.ErrNoFilter when params sets no filter, and rows deleted before are left alone
+	DeleteWhere(ctx context.Context, params *List{{.ModelName}}Params) (rows int64, err error)
+{{- if .DeletedField}}
+	// Restore undoes the soft delete of the rows by id
+	Restore(ctx context.Context, ids ...{{.PKType}}) (rows int64, err error)
+{{- end}}
+	// WithTx returns a copy of the dao bound to tx
+	WithTx(tx *gorm.DB) I{{.ModelName}}Dao
+}
+{{if ne .InterfaceName (print "I" .ModelName "Dao")}}
+// I{{.ModelName}}Dao is the exported name of {{.InterfaceName}}, it lets the mock package implement it
+type I{{.ModelName}}Dao = {{.InterfaceName}}
+{{end}}
+// New{{.ModelName}}Dao returns a {{.ModelName}} dao on db, for services that inject their DAOs instead of using Init
+func New{{.ModelName}}Dao(db *gorm.DB) I{{.ModelName}}Dao {
+	return &{{.DaoName}}{db: db}
+}
+
+// {{.ModelName}}SortColumns are the columns List{{.ModelName}}Params.OrderBy may sort by
+var {{.ModelName}}SortColumns = []string{ {{- range $i, $f := .SortFields}}{{if $i}}, {{end}}"{{$f}}"{{end -}} }
+
+// {{.ModelName}}Columns are the columns Fields of List{{.ModelName}}Params and WithFields may select
+var {{.ModelName}}Columns = []string{ {{- range $i, $f := .Columns}}{{if $i}}, {{end}}"{{$f}}"{{end -}} }
+
+{{- if .Relations}}
+// associations of {{.ModelName}}, for Preload and WithPreload
+const (
+{{- range .Relations}}
+	{{$.ModelName}}Relation{{replace . "." ""}} = "{{.}}"
+{{- end}}
+)
+{{end}}
 // List{{.ModelName}}Params represents the params to list models
 type List{{.ModelName}}Params struct {
 	ezgen.Pager
+	Cursor ezgen.CursorPager // optional, keyset pagination, takes precedence over Pager
+	OrderBy []ezgen.Sort // optional, columns from {{.ModelName}}SortColumns, the primary key breaks ties. Not with Cursor
+	Fields []string // optional, columns from {{.ModelName}}Columns to select, the primary key and the sort column are always selected
+	Preload []string // optional, associations to preload, nil preloads all of them and an empty slice none
+	PreloadConds map[string][]any // optional, conditions of preloaded associations by name, as gorm's Preload args
 
 {{range $element := .ParamsKey}}
     {{$element}}
 {{- end}}
 
-	Deleted bool // optional
-	Cached constants.CacheMode // optional
+	Deleted bool // optional, include soft-deleted rows
+	DeletedMode ezgen.DeletedMode // optional, whether soft-deleted rows are excluded (default), included or the only ones listed, takes precedence over Deleted
+	Cached ezgen.CacheMode // optional
+	Primary bool // optional, read from the primary instead of a replica
+	Count ezgen.CountStrategy // optional, how List computes total, exact by default. With Cursor only an explicit exact strategy counts, others count has-more
+
+	NextCursor string // output, set by List when Cursor is used and more rows remain
+}
+
+// deletedMode combines Deleted and DeletedMode
+func (params *List{{.ModelName}}Params) deletedMode() ezgen.DeletedMode {
+	return ezgen.DeletedModeOf(params.Deleted, params.DeletedMode)
+}
+
+// filtered reports whether params sets any filter, DeleteWhere refuses to run without one
+func (params *List{{.ModelName}}Params) filtered() bool {
+{{- if .FilterConds}}
+	return {{range $i, $c := .FilterConds}}{{if $i}} ||
+		{{end}}{{$c}}{{end}}
+{{- else}}
+	return false
+{{- end}}
+}
+
+// WithTx binds a copy of the dao to tx. Call it with a ctx from ezgen.TrackWrites to invalidate cached queries
+// again once tx commits.
+func (dao *{{.DaoName}}) WithTx(tx *gorm.DB) I{{.ModelName}}Dao {
+	return &{{.DaoName}}{db: tx}
+}
+
+// conn returns the transaction carried by ctx (see Transaction), or dao.db
+func (dao *{{.DaoName}}) conn(ctx context.Context) *gorm.DB {
+	return ezgen.Conn(ctx, dao.db)
+}
+
+// invalidate drops the cached queries of the table and of its associations after a write, see ezgen.CachePlugin
+func (dao *{{.DaoName}}) invalidate(ctx context.Context, err error) error {
+	if err == nil {
+		ezgen.InvalidateCache(ctx, dao.db, ezgen.ModelTables(dao.db, model.TableName{{.ModelName}}, &model.{{.ModelName}}{})...)
+	}
+	return err
 }
 
 func (dao *{{.DaoName}}) Add(ctx context.Context, data ...*model.{{.ModelName}}) (err error) {
-	q := query.Use(dao.db).{{.ModelName}}
-    return q.WithContext(ctx).Create(data...)
+	q := query.Use(dao.conn(ctx)).{{.ModelName}}
+	return dao.invalidate(ctx, q.WithContext(ctx).Create(data...))
+}
+
+// Upsert inserts data in batches of {{.UpsertBatchSize}}, or of the size set by ezgen.ContextWithBatchSize.
+// Rows conflicting on conflictColumns (default: {{range $i, $f := .ConflictFields}}{{if $i}}, {{end}}{{$f}}{{end}}) get updateColumns (default: all columns) updated.
+{{- if .UniqueFields}}
+// conflictColumns must be the primary key or the columns of one unique index, such as {{range $i, $f := .UniqueFields}}{{if $i}}, {{end}}{{$f}}{{end}}.
+{{- else}}
+// conflictColumns must be the primary key or the columns of one unique index.
+{{- end}}
+// MySQL ignores conflictColumns and uses every unique key.
+func (dao *{{.DaoName}}) Upsert(ctx context.Context, conflictColumns, updateColumns []string, data ...*model.{{.ModelName}}) (err error) {
+	if len(conflictColumns) == 0 {
+		conflictColumns = []string{ {{- range $i, $f := .ConflictFields}}{{if $i}}, {{end}}"{{$f}}"{{end -}} }
+	}
+	q := query.Use(dao.conn(ctx)).{{.ModelName}}
+	err = q.WithContext(ctx).
+		Clauses(ezgen.OnConflict(conflictColumns, updateColumns)).
+		CreateInBatches(data, ezgen.BatchSizeFromContext(ctx, {{.UpsertBatchSize}}))
+	return dao.invalidate(ctx, err)
 }
 
 func (dao *{{.DaoName}}) Get(ctx context.Context, id {{.PKType}}, opts ...GetOption) (result *model.{{.ModelName}}, err error) {
 	cfg := &getConfig{}
 	for _, opt := range opts { opt(cfg) }
-	switch cfg.Cached {
-    	case constants.ModeCached:
-    		ctx = context.WithValue(ctx, constants.ModeKey, constants.ModeCached)
-    	case constants.ModeWarm:
-    		ctx = context.WithValue(ctx, constants.ModeKey, constants.ModeWarm)
-    	default:
-	}
-	err = dao.db.WithContext(ctx).Table(model.TableName{{.ModelName}}).
-		Preload(clause.Associations).
-		Scopes(ezgen.WithDeleted(cfg.WithDeleted)).
+	ctx = ezgen.WithCacheMode(ctx, cfg.Cached)
+	err = dao.conn(ctx).Table(model.TableName{{.ModelName}}).
+		Scopes(ezgen.PreloadScope(cfg.Preload, cfg.PreloadConds)).
+		Scopes(ezgen.SelectScope(cfg.Fields, {{.ModelName}}Columns, "{{.PrimaryField}}", "{{.SortField}}")).
+		Scopes(ezgen.WithDeletedMode(cfg.deletedMode())).
+		Scopes(ezgen.WithPrimary(cfg.Primary)).
 		Where("{{ .PrimaryField }} = ?", id).
 		First(&result).
 		Error
@@ -63,47 +188,226 @@ func (dao *{{.DaoName}}) List(ctx context.Context, params *List{{.ModelName}}Par
 	if params == nil {
 		params = &List{{.ModelName}}Params{}
 	}
-	switch params.Cached {
-	case constants.ModeCached:
-		ctx = context.WithValue(ctx, constants.ModeKey, constants.ModeCached)
-	case constants.ModeWarm:
-		ctx = context.WithValue(ctx, constants.ModeKey, constants.ModeWarm)
-	default:
-	}
-	tx := dao.db.WithContext(ctx).Table(model.TableName{{.ModelName}}).
-		Preload(clause.Associations).
-		Scopes(ezgen.WithDeleted(params.Deleted)).
-		Scopes(ezgen.Paginate(params.Pager)).
-	{{- range $element := .ParamsScopes}}
-        {{$element}}
-    {{- end}}
-	{{ if .Desc }}
-		Order("{{ .SortField }} desc")
-	{{ else }}
-		Order("{{ .SortField }} asc")
-	{{ end }}
-
-	total, err = ezgen.FindAndCountTransaction(tx, &list)
+	pager := params.Pager
+	if params.Cursor != nil {
+		if len(params.OrderBy) > 0 {
+			return nil, 0, fmt.Errorf("%w: OrderBy can not be used with Cursor", ezgen.ErrInvalidSort)
+		}
+		pager = nil
+		params.NextCursor = ""
+	}
+	tx := dao.filter(ctx, params).
+		Scopes(ezgen.PreloadScope(params.Preload, params.PreloadConds)).
+		Scopes(ezgen.SelectScope(params.Fields, {{.ModelName}}Columns, "{{.PrimaryField}}", "{{.SortField}}")).
+		Scopes(ezgen.Paginate(pager)).
+		Scopes(ezgen.OrderScope(params.OrderBy, {{.ModelName}}SortColumns, ezgen.Sort{Column: "{{.SortField}}", Desc: {{.Desc}}}, "{{.PrimaryField}}")).
+		// OrderScope already ends with the primary key, PaginateCursor does not add it again
+		Scopes(ezgen.PaginateCursor(params.Cursor, "{{.SortField}}", "{{.PrimaryField}}", {{.Desc}}))
+
+	total, err = ezgen.FindAndCount(tx, &list, ezgen.ListStrategy(params.Count, params.Cursor != nil))
 	if err != nil {
 		return nil, 0, err
 	}
+	// with a cursor, total counts the rows after it
+	if params.Cursor != nil && len(list) > 0 && total > int64(len(list)) {
+		last := list[len(list)-1]
+		params.NextCursor, err = ezgen.EncodeCursor(last.{{.SortGoField}}, last.{{.PrimaryGoField}})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
 
 	return list, total, nil
 }
 
+func (dao *{{.DaoName}}) Count(ctx context.Context, params *List{{.ModelName}}Params) (count int64, err error) {
+	if params == nil {
+		params = &List{{.ModelName}}Params{}
+	}
+	err = dao.filter(ctx, params).Count(&count).Error
+	return count, err
+}
+
+func (dao *{{.DaoName}}) Exists(ctx context.Context, params *List{{.ModelName}}Params) (exists bool, err error) {
+	if params == nil {
+		params = &List{{.ModelName}}Params{}
+	}
+	return ezgen.Exists(dao.filter(ctx, params))
+}
+
+func (dao *{{.DaoName}}) CountBy(ctx context.Context, params *List{{.ModelName}}Params, column string) (counts []ezgen.GroupCount, err error) {
+	if params == nil {
+		params = &List{{.ModelName}}Params{}
+	}
+	if err = ezgen.ValidateFields([]string{column}, {{.ModelName}}SortColumns); err != nil {
+		return nil, err
+	}
+	return ezgen.CountBy[model.{{.ModelName}}](dao.filter(ctx, params), column)
+}
+
+func (dao *{{.DaoName}}) Each(ctx context.Context, params *List{{.ModelName}}Params, batchSize int, fn func(batch []*model.{{.ModelName}}) error) (err error) {
+	if params == nil {
+		params = &List{{.ModelName}}Params{}
+	}
+	if err = ezgen.ValidateFields(params.Fields, {{.ModelName}}Columns); err != nil {
+		return err
+	}
+	scoped := func() *gorm.DB {
+		return dao.filter(ctx, params).
+			Scopes(ezgen.PreloadScope(params.Preload, params.PreloadConds)).
+			Scopes(ezgen.SelectScope(params.Fields, {{.ModelName}}Columns, "{{.PrimaryField}}", "{{.SortField}}"))
+	}
+	return ezgen.EachBatch(ctx, scoped, "{{.PrimaryField}}", batchSize, func(row *model.{{.ModelName}}) any {
+		return row.{{.PrimaryGoField}}
+	}, fn)
+}
+
+func (dao *{{.DaoName}}) Iter(ctx context.Context, params *List{{.ModelName}}Params) iter.Seq2[*model.{{.ModelName}}, error] {
+	return ezgen.Iter(func(fn func(batch []*model.{{.ModelName}}) error) error {
+		return dao.Each(ctx, params, ezgen.DefaultBatchSize, fn)
+	})
+}
+
+// filter returns the query of the rows matching the filters of params, shared by List and the aggregate methods
+func (dao *{{.DaoName}}) filter(ctx context.Context, params *List{{.ModelName}}Params) *gorm.DB {
+	tx := dao.conn(ezgen.WithCacheMode(ctx, params.Cached)).Model(&model.{{.ModelName}}{}).Table(model.TableName{{.ModelName}})
+	// applied now rather than as a scope, so that the CountExact transaction opens on the primary too
+	return ezgen.WithPrimary(params.Primary)(tx).
+	{{- range $element := .ParamsScopes}}
+		{{$element}}
+	{{- end}}
+		Scopes(ezgen.WithDeletedMode(params.deletedMode()))
+}
+
+{{- if .VersionField}}
+// Update updates the non-zero fields of data, it returns ezgen.ErrOptimisticLockConflict
+// if data.{{.VersionGoField}} is set and no longer matches the row
+{{- end}}
 func (dao *{{.DaoName}}) Update(ctx context.Context, data *model.{{.ModelName}}) (err error) {
-	return dao.db.WithContext(ctx).Table(model.TableName{{.ModelName}}).Updates(data).Error
+{{- if .VersionField}}
+	// the version is captured up front, the optimistic lock plugin resets it while updating
+	locked := data.{{.VersionGoField}}.Valid
+	result := dao.conn(ctx).Table(model.TableName{{.ModelName}}).Updates(data)
+	if result.Error == nil && locked && result.RowsAffected == 0 {
+		return ezgen.ErrOptimisticLockConflict
+	}
+	return dao.invalidate(ctx, result.Error)
+{{- else}}
+	return dao.invalidate(ctx, dao.conn(ctx).Table(model.TableName{{.ModelName}}).Updates(data).Error)
+{{- end}}
+}
+
+// UpdateColumns updates the given columns of the row by id, zero values included.
+{{- if .VersionField}}
+// Pass the current version as columns["{{.VersionField}}"] to enable the optimistic lock check,
+// ezgen.ErrOptimisticLockConflict is returned if it no longer matches the row.
+{{- end}}
+func (dao *{{.DaoName}}) UpdateColumns(ctx context.Context, id {{.PKType}}, columns map[string]any) (rows int64, err error) {
+	if len(columns) == 0 {
+		return 0, ezgen.ErrNoColumns
+	}
+	tx := dao.conn(ctx).Model(&model.{{.ModelName}}{ {{.PrimaryGoField}}: id })
+{{- if .VersionField}}
+	columns, version, ok := ezgen.SplitVersion(columns, "{{.VersionField}}")
+	if ok {
+		tx = tx.Where("{{.VersionField}} = ?", version)
+	}
+{{- end}}
+	result := tx.Updates(columns)
+{{- if .VersionField}}
+	if result.Error == nil && ok && result.RowsAffected == 0 {
+		return 0, ezgen.ErrOptimisticLockConflict
+	}
+{{- end}}
+	return result.RowsAffected, dao.invalidate(ctx, result.Error)
+}
+
+// UpdateSelect updates the selected columns of data by its primary key, zero values included.
+{{- if .VersionField}}
+// ezgen.ErrOptimisticLockConflict is returned if data.{{.VersionGoField}} is set and no longer matches the row.
+{{- end}}
+func (dao *{{.DaoName}}) UpdateSelect(ctx context.Context, data *model.{{.ModelName}}, columns ...string) (rows int64, err error) {
+	if len(columns) == 0 {
+		return 0, ezgen.ErrNoColumns
+	}
+{{- if .VersionField}}
+	// the version column must be selected so that the optimistic lock can bump it
+	columns = append(slices.Clip(columns), "{{.VersionField}}")
+	locked := data.{{.VersionGoField}}.Valid
+{{- end}}
+	result := dao.conn(ctx).Model(data).Select(columns).Updates(data)
+{{- if .VersionField}}
+	if result.Error == nil && locked && result.RowsAffected == 0 {
+		return 0, ezgen.ErrOptimisticLockConflict
+	}
+{{- end}}
+	return result.RowsAffected, dao.invalidate(ctx, result.Error)
 }
 
 func (dao *{{.DaoName}}) Delete(ctx context.Context, id {{.PKType}}) (err error) {
-	return dao.db.WithContext(ctx).Table(model.TableName{{.ModelName}}).
+	err = dao.conn(ctx).Table(model.TableName{{.ModelName}}).
 		Select(clause.Associations).
 		Delete(&model.{{.ModelName}}{ {{.PrimaryGoField}}: id }).Error
+	return dao.invalidate(ctx, err)
 }
 
 func (dao *{{.DaoName}}) Destroy(ctx context.Context, id {{.PKType}}) (err error) {
-	return dao.db.WithContext(ctx).Table(model.TableName{{.ModelName}}).
+	err = dao.conn(ctx).Table(model.TableName{{.ModelName}}).
 		Select(clause.Associations).
 		Unscoped().
 		Delete(&model.{{.ModelName}}{ {{.PrimaryGoField}}: id }).Error
+	return dao.invalidate(ctx, err)
+}
+
+func (dao *{{.DaoName}}) DeleteByIDs(ctx context.Context, ids ...{{.PKType}}) (rows int64, err error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := dao.conn(ctx).Table(model.TableName{{.ModelName}}).
+		Select(clause.Associations).
+		Delete(byIDs{{.ModelName}}(ids))
+	return result.RowsAffected, dao.invalidate(ctx, result.Error)
+}
+
+func (dao *{{.DaoName}}) DestroyByIDs(ctx context.Context, ids ...{{.PKType}}) (rows int64, err error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := dao.conn(ctx).Table(model.TableName{{.ModelName}}).
+		Select(clause.Associations).
+		Unscoped().
+		Delete(byIDs{{.ModelName}}(ids))
+	return result.RowsAffected, dao.invalidate(ctx, result.Error)
+}
+
+func (dao *{{.DaoName}}) DeleteWhere(ctx context.Context, params *List{{.ModelName}}Params) (rows int64, err error) {
+	if params == nil || !params.filtered() {
+		return 0, ezgen.ErrNoFilter
+	}
+	scoped := *params
+	scoped.Deleted, scoped.DeletedMode = false, ezgen.DeletedExclude
+	result := dao.filter(ctx, &scoped).Delete(&model.{{.ModelName}}{})
+	return result.RowsAffected, dao.invalidate(ctx, result.Error)
+}
+{{if .DeletedField}}
+func (dao *{{.DaoName}}) Restore(ctx context.Context, ids ...{{.PKType}}) (rows int64, err error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	undeleted := ezgen.Undeleted(model.{{.ModelName}}{}.{{.DeletedGoField}})
+	result := dao.conn(ctx).Model(&model.{{.ModelName}}{}).
+		Unscoped().
+		Where("{{.PrimaryField}} in ?", ids).
+		Where(clause.Neq{Column: clause.Column{Name: "{{.DeletedField}}"}, Value: undeleted}).
+		Update("{{.DeletedField}}", undeleted)
+	return result.RowsAffected, dao.invalidate(ctx, result.Error)
+}
+{{end}}
+// byIDs{{.ModelName}} returns models carrying only the primary keys, so that associations are deleted with them
+func byIDs{{.ModelName}}(ids []{{.PKType}}) []*model.{{.ModelName}} {
+	data := make([]*model.{{.ModelName}}, len(ids))
+	for i, id := range ids {
+		data[i] = &model.{{.ModelName}}{ {{.PrimaryGoField}}: id }
+	}
+	return data
 }
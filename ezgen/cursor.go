@@ -0,0 +1,69 @@
+package ezgen
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("ezgen: invalid cursor")
+
+func init() {
+	// gob 只预注册了基础类型, 时间类型的排序字段需要额外注册
+	gob.Register(time.Time{})
+}
+
+// Cursor 表示 keyset 分页中上一页最后一行的位置
+type Cursor struct {
+	Sort any // 排序字段的值
+	PK   any // 主键的值
+}
+
+// EncodeCursor 将最后一行的排序字段和主键编码为不透明的游标
+func EncodeCursor(sort, pk any) (string, error) {
+	c := Cursor{Sort: indirect(sort), PK: indirect(pk)}
+	if c.Sort == nil || c.PK == nil {
+		return "", fmt.Errorf("%w: nil cursor value", ErrInvalidCursor)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&c); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DecodeCursor 解析 EncodeCursor 生成的游标
+func DecodeCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	c := &Cursor{}
+	if err = gob.NewDecoder(bytes.NewReader(raw)).Decode(c); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if c.Sort == nil || c.PK == nil {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func indirect(v any) any {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	return rv.Interface()
+}
@@ -0,0 +1,111 @@
+package ezgen
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	name := "bob"
+	created := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	tests := []struct {
+		name     string
+		sort, pk any
+		wantSort any
+		wantPK   any
+	}{
+		{name: "int", sort: int64(10), pk: int64(10), wantSort: int64(10), wantPK: int64(10)},
+		{name: "string pk", sort: "b", pk: "code-1", wantSort: "b", wantPK: "code-1"},
+		{name: "pointer", sort: &name, pk: uint32(7), wantSort: "bob", wantPK: uint32(7)},
+		{name: "time", sort: created, pk: int64(1), wantSort: created, wantPK: int64(1)},
+		{name: "float", sort: 1.5, pk: int64(2), wantSort: 1.5, wantPK: int64(2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := EncodeCursor(tt.sort, tt.pk)
+			if err != nil {
+				t.Fatalf("EncodeCursor() error = %v", err)
+			}
+			c, err := DecodeCursor(token)
+			if err != nil {
+				t.Fatalf("DecodeCursor() error = %v", err)
+			}
+			if !reflect.DeepEqual(c.Sort, tt.wantSort) || !reflect.DeepEqual(c.PK, tt.wantPK) {
+				t.Errorf("DecodeCursor() = {%#v %#v}, want {%#v %#v}", c.Sort, c.PK, tt.wantSort, tt.wantPK)
+			}
+		})
+	}
+}
+
+func TestCursorInvalid(t *testing.T) {
+	var nilName *string
+	if _, err := EncodeCursor(nilName, int64(1)); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("EncodeCursor(nil) error = %v, want ErrInvalidCursor", err)
+	}
+	for _, token := range []string{"", "!!", "bm90IGdvYg"} {
+		if _, err := DecodeCursor(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", token, err)
+		}
+	}
+}
+
+// valuePager implements CursorPager and Pager on a value receiver, reflect.Value.IsNil panics on it
+type valuePager struct {
+	size   uint32
+	cursor string
+}
+
+func (p valuePager) GetPageSize() uint32  { return p.size }
+func (p valuePager) GetPageIndex() uint32 { return 1 }
+func (p valuePager) GetCursor() string    { return p.cursor }
+
+func TestPaginateValuePager(t *testing.T) {
+	var nilPager *valuePager
+	tests := []struct {
+		name  string
+		pager any
+		want  bool
+	}{
+		{name: "nil", pager: nil, want: true},
+		{name: "nil pointer", pager: nilPager, want: true},
+		{name: "value", pager: valuePager{size: 10}},
+		{name: "pointer", pager: &valuePager{size: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNil(tt.pager); got != tt.want {
+				t.Errorf("isNil() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	// the scopes must not panic on a value pager
+	db := dryRunDB(t)
+	var rows []struct{ ID int64 }
+	stmt := db.Table("users").Scopes(PaginateCursor(valuePager{size: 10}, "id", "id", true), Paginate(valuePager{size: 10})).Find(&rows).Statement
+	if sql := stmt.SQL.String(); !strings.Contains(sql, "LIMIT") {
+		t.Errorf("SQL = %s, want a LIMIT", sql)
+	}
+}
+
+func TestListStrategy(t *testing.T) {
+	tests := []struct {
+		strategy CountStrategy
+		cursor   bool
+		want     CountStrategy
+	}{
+		{strategy: CountDefault, want: CountExact},
+		{strategy: CountNone, want: CountNone},
+		{strategy: CountDefault, cursor: true, want: CountHasMore},
+		{strategy: CountEstimated, cursor: true, want: CountHasMore},
+		{strategy: CountExact, cursor: true, want: CountExact},
+		{strategy: CountExactNoTx, cursor: true, want: CountExactNoTx},
+	}
+	for _, tt := range tests {
+		if got := ListStrategy(tt.strategy, tt.cursor); got != tt.want {
+			t.Errorf("ListStrategy(%d, %v) = %d, want %d", tt.strategy, tt.cursor, got, tt.want)
+		}
+	}
+}
@@ -2,42 +2,150 @@
 // Code generated by ezgen. DO NOT EDIT.
 // Code generated by ezgen. DO NOT EDIT.
 
-package dao
+package {{.DaoPkgName}}
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"sync"
 
-	"{{.ModelPackage}}/internal/constants"
+	"github.com/ez4bk/gen-ext/ezgen"
 
 	"gorm.io/gorm"
 )
 
 var (
 	initOnce sync.Once
-	initMockOnce sync.Once
+	defaultDB *gorm.DB
 
 	{{range $index, $modelName := .ModelNameList}}
-	{{$modelName}} i{{$modelName}}Dao
+	{{$modelName}} I{{$modelName}}Dao
 	{{- end}}
 )
 
-// Init initializes all Dao structures
-func Init(mysql *gorm.DB) {
-	initOnce.Do(func() {
-		{{range $index, $modelName := .ModelNameList}}
-		{{$modelName}} = &{{index $.DaoNameList $index}}{db: mysql}
+// Registry holds one instance of every DAO on the same database. Services can inject it (or single DAOs
+// from New{{"{{Model}}"}}Dao) instead of relying on the package level DAOs set by Init.
+type Registry struct {
+	db *gorm.DB
+	{{range $index, $modelName := .ModelNameList}}
+	{{$modelName}} I{{$modelName}}Dao
+	{{- end}}
+}
+
+// NewRegistry creates all DAOs on db. Unlike Init it does not register replicas, see ezgen.UseReplicas.
+func NewRegistry(db *gorm.DB) *Registry {
+	return &Registry{
+		db: db,
+		{{- range $index, $modelName := .ModelNameList}}
+		{{$modelName}}: New{{$modelName}}Dao(db),
 		{{- end}}
+	}
+}
+
+// Transaction runs fn in a transaction on the registry's database, see Transaction
+func (r *Registry) Transaction(ctx context.Context, fn func(ctx context.Context) error, opts ...*sql.TxOptions) error {
+	return ezgen.Transaction(ctx, r.db, fn, opts...)
+}
+
+// Registries holds the Registry of each tenant or datasource, created on first use
+// from the database tenants opens for it
+type Registries struct {
+	tenants *ezgen.Tenants
+	mu sync.Mutex
+	registries map[string]*Registry
+}
+
+// NewRegistries creates an empty Registries on tenants
+func NewRegistries(tenants *ezgen.Tenants) *Registries {
+	return &Registries{tenants: tenants, registries: make(map[string]*Registry)}
+}
+
+// Get returns the Registry of the named tenant, opening its database on first use
+func (r *Registries) Get(ctx context.Context, name string) (*Registry, error) {
+	db, err := r.tenants.DB(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	registry, ok := r.registries[name]
+	// the database is replaced when the tenant has been removed and opened again
+	if !ok || registry.db != db {
+		registry = NewRegistry(db)
+		r.registries[name] = registry
+	}
+	return registry, nil
+}
+
+// FromContext returns the Registry of the tenant set by ezgen.WithTenant
+func (r *Registries) FromContext(ctx context.Context) (*Registry, error) {
+	name, ok := ezgen.TenantFrom(ctx)
+	if !ok {
+		return nil, ezgen.ErrNoTenant
+	}
+	return r.Get(ctx, name)
+}
+
+// Init initializes all Dao structures. Reads go to the replicas when given (see WithPrimary),
+// writes and transactions always use mysql. It panics if the replicas can not be registered.
+// Pass tenants.Route(mysql) to resolve the database per request from ezgen.WithTenant.
+func Init(mysql *gorm.DB, replicas ...gorm.Dialector) {
+	initOnce.Do(func() {
+		if err := ezgen.UseReplicas(mysql, replicas...); err != nil {
+			panic(err)
+		}
+		Use(NewRegistry(mysql))
 	})
 }
 
+// Use installs the DAOs of r as the package level DAOs. Unlike Init it can be called again,
+// but not concurrently with the DAOs in use.
+func Use(r *Registry) {
+	defaultDB = r.db
+	{{- range $index, $modelName := .ModelNameList}}
+	{{$modelName}} = r.{{$modelName}}
+	{{- end}}
+}
+
+// InitMock installs mocks (see the mock package) in place of the DAOs whose interface they implement.
+// Unlike Init it replaces the DAOs on every call, so each test can start from fresh mocks.
+func InitMock(mocks ...any) {
+	for _, m := range mocks {
+		switch m := m.(type) {
+		{{- range $index, $modelName := .ModelNameList}}
+		case I{{$modelName}}Dao:
+			{{$modelName}} = m
+		{{- end}}
+		default:
+			panic(fmt.Sprintf("dao: %T implements no dao interface", m))
+		}
+	}
+}
+
+// Transaction runs fn in a transaction, dao methods called with the ctx passed to fn join it.
+// Nested calls use savepoints. The transaction is rolled back if fn returns an error or panics.
+// It returns ezgen.ErrNoDB before Init or Use has been called.
+func Transaction(ctx context.Context, fn func(ctx context.Context) error, opts ...*sql.TxOptions) error {
+	if defaultDB == nil {
+		return ezgen.ErrNoDB
+	}
+	return ezgen.Transaction(ctx, defaultDB, fn, opts...)
+}
+
 type getConfig struct {
-	Cached constants.CacheMode
+	Cached ezgen.CacheMode
 	WithDeleted bool
+	DeletedMode ezgen.DeletedMode
+	Primary bool
+	Fields []string
+	Preload []string
+	PreloadConds map[string][]any
 }
 
 type GetOption func(*getConfig)
 
-func WithCached(cached constants.CacheMode) GetOption {
+func WithCached(cached ezgen.CacheMode) GetOption {
 	return func(cfg *getConfig) {
 		cfg.Cached = cached
 	}
@@ -48,3 +156,57 @@ func WithDeleted(withDeleted bool) GetOption {
 		cfg.WithDeleted = withDeleted
 	}
 }
+
+// WithDeletedMode sets how Get treats soft-deleted rows, ezgen.DeletedOnly finds only a soft-deleted row.
+// It takes precedence over WithDeleted unless it is ezgen.DeletedExclude
+func WithDeletedMode(mode ezgen.DeletedMode) GetOption {
+	return func(cfg *getConfig) {
+		cfg.DeletedMode = mode
+	}
+}
+
+func (cfg *getConfig) deletedMode() ezgen.DeletedMode {
+	return ezgen.DeletedModeOf(cfg.WithDeleted, cfg.DeletedMode)
+}
+
+// WithPrimary reads from the primary instead of a replica, for read-after-write consistency
+func WithPrimary() GetOption {
+	return func(cfg *getConfig) {
+		cfg.Primary = true
+	}
+}
+
+// WithFields selects only the given columns (see {{"{{Model}}"}}Columns), the primary key is always selected
+func WithFields(fields ...string) GetOption {
+	return func(cfg *getConfig) {
+		cfg.Fields = fields
+	}
+}
+
+// WithPreload preloads only the given associations (see the {{"{{Model}}"}}Relation constants), nested ones
+// as "Orders.Items". All associations are preloaded by default.
+func WithPreload(names ...string) GetOption {
+	return func(cfg *getConfig) {
+		cfg.Preload = append(cfg.Preload, names...)
+		if cfg.Preload == nil {
+			cfg.Preload = []string{}
+		}
+	}
+}
+
+// WithoutPreload preloads no association
+func WithoutPreload() GetOption {
+	return func(cfg *getConfig) {
+		cfg.Preload = []string{}
+	}
+}
+
+// WithPreloadConds preloads the association name with conditions, as gorm's Preload args
+func WithPreloadConds(name string, conds ...any) GetOption {
+	return func(cfg *getConfig) {
+		if cfg.PreloadConds == nil {
+			cfg.PreloadConds = make(map[string][]any)
+		}
+		cfg.PreloadConds[name] = conds
+	}
+}
@@ -0,0 +1,106 @@
+package ezgen
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gen"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrNoFilter DeleteWhere 的参数没有设置任何过滤条件, 拒绝删除整张表
+var ErrNoFilter = errors.New("ezgen: no filter set")
+
+// DeletedMode 查询对软删除行的处理方式
+type DeletedMode int
+
+const (
+	DeletedExclude DeletedMode = iota // 只查询未删除的行
+	DeletedInclude                    // 查询全部行, 同 Unscoped
+	DeletedOnly                       // 只查询已软删除的行
+)
+
+// DeletedModeOf 合并 List 参数和 Get 选项中布尔的 Deleted 和 DeletedMode, mode 不是 DeletedExclude 时优先,
+// 否则 deleted 为 true 时查询全部行
+func DeletedModeOf(deleted bool, mode DeletedMode) DeletedMode {
+	if mode == DeletedExclude && deleted {
+		return DeletedInclude
+	}
+	return mode
+}
+
+// Undeleted 返回软删除字段 deleted 未删除时的列值, gorm.DeletedAt(及其指针)为 NULL, soft_delete.DeletedAt(时间戳或 flag)为 0
+func Undeleted(deleted any) any {
+	switch deleted.(type) {
+	case gorm.DeletedAt, *gorm.DeletedAt:
+		return nil
+	}
+	return 0
+}
+
+// WithDeletedMode 按 mode 处理软删除行. DeletedOnly 的条件在生成 SQL 时由模型的软删除字段决定:
+// gorm.DeletedAt 为 IS NOT NULL, soft_delete.DeletedAt(时间戳或 flag)为 <> 0. 模型没有软删除字段时不匹配任何行
+func WithDeletedMode(mode DeletedMode) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch mode {
+		case DeletedExclude:
+			return db
+		case DeletedInclude:
+			return db.Unscoped()
+		case DeletedOnly:
+			return db.Unscoped().Where(onlyDeleted{})
+		}
+		_ = db.AddError(checkDeletedMode(mode))
+		return db
+	}
+}
+
+func WithDeletedModeGen(mode DeletedMode) func(db gen.Dao) gen.Dao {
+	return func(db gen.Dao) gen.Dao {
+		switch mode {
+		case DeletedExclude:
+			return db
+		case DeletedInclude:
+			return db.Unscoped()
+		case DeletedOnly:
+			return db.Unscoped().Where(gen.Cond(onlyDeleted{})...)
+		}
+		_ = db.AddError(checkDeletedMode(mode))
+		return db
+	}
+}
+
+func checkDeletedMode(mode DeletedMode) error {
+	switch mode {
+	case DeletedExclude, DeletedInclude, DeletedOnly:
+		return nil
+	}
+	return fmt.Errorf("ezgen: unknown deleted mode %d", mode)
+}
+
+// onlyDeleted 匹配已软删除的行, 软删除字段在构建时从 Statement 的模型中查找
+type onlyDeleted struct{}
+
+func (onlyDeleted) Build(builder clause.Builder) {
+	stmt, ok := builder.(*gorm.Statement)
+	if !ok || stmt.Schema == nil {
+		builder.WriteString("1 = 0")
+		return
+	}
+	for _, field := range stmt.Schema.Fields {
+		// gorm.DeletedAt 和 soft_delete.DeletedAt 都通过 DeleteClauses 实现软删除
+		value := reflect.New(field.IndirectFieldType)
+		if _, ok := value.Interface().(schema.DeleteClausesInterface); !ok || field.DBName == "" {
+			continue
+		}
+		clause.Neq{
+			Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName},
+			Value:  Undeleted(value.Elem().Interface()),
+		}.Build(builder)
+		return
+	}
+	builder.WriteString("1 = 0")
+}
@@ -0,0 +1,160 @@
+package ezgen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const diffContext = 3
+
+// UnifiedDiff 返回 old 到 new 的 unified diff, 内容相同时返回空字符串
+func UnifiedDiff(oldName, newName string, old, new []byte) string {
+	if bytes.Equal(old, new) {
+		return ""
+	}
+	a, b := splitLines(old), splitLines(new)
+	ops := diffLines(a, b)
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+	for i := 0; i < len(ops); {
+		// 找到下一处变化, 连同前后的上下文组成一个 hunk
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		start := max(i-diffContext, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			same := 0
+			for end+same < len(ops) && ops[end+same].kind == ' ' {
+				same++
+			}
+			if end+same == len(ops) || same > 2*diffContext {
+				end += min(same, diffContext)
+				break
+			}
+			end += same
+		}
+		writeHunk(&buf, ops[start:end])
+		i = end
+	}
+	return buf.String()
+}
+
+type diffOp struct {
+	kind  byte // ' ', '-', '+'
+	line  string
+	aLine int // 1-based line number in old
+	bLine int // 1-based line number in new
+}
+
+func writeHunk(buf *strings.Builder, ops []diffOp) {
+	aStart, bStart, aCount, bCount := 0, 0, 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			if aCount == 0 {
+				aStart = op.aLine
+			}
+			aCount++
+		}
+		if op.kind != '-' {
+			if bCount == 0 {
+				bStart = op.bLine
+			}
+			bCount++
+		}
+	}
+	if aCount == 0 {
+		aStart = ops[0].aLine - 1
+	}
+	if bCount == 0 {
+		bStart = ops[0].bLine - 1
+	}
+	fmt.Fprintf(buf, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+	for _, op := range ops {
+		buf.WriteByte(op.kind)
+		buf.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			buf.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// diffLines 基于最长公共子序列计算逐行差异
+func diffLines(a, b []string) []diffOp {
+	// 去掉公共前缀和后缀, 缩小 LCS 表
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	lcs := make([][]int32, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	ai, bi := 0, 0
+	keep := func() {
+		ops = append(ops, diffOp{kind: ' ', line: a[ai], aLine: ai + 1, bLine: bi + 1})
+		ai++
+		bi++
+	}
+	for ai < prefix {
+		keep()
+	}
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			keep()
+			i++
+			j++
+		case i < len(ma) && (j == len(mb) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{kind: '-', line: a[ai], aLine: ai + 1, bLine: bi + 1})
+			ai++
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: b[bi], aLine: ai + 1, bLine: bi + 1})
+			bi++
+			j++
+		}
+	}
+	for ai < len(a) {
+		keep()
+	}
+	return ops
+}
+
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
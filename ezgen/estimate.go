@@ -0,0 +1,124 @@
+package ezgen
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// estimateCount 估算已执行查询的 db 分页前的行数, 不执行 COUNT(*).
+// 没有查询条件时读表的统计信息(MySQL information_schema.TABLES, PostgreSQL pg_class), 否则读 EXPLAIN 的估算行数.
+// 其他数据库或统计信息不可用时精确统计
+func estimateCount(db *gorm.DB, result interface{}) (int64, error) {
+	// DryRun 只生成 SQL, 用空的 result 避免 Preload 改写已查询到的数据
+	dest := reflect.New(reflect.Indirect(reflect.ValueOf(result)).Type()).Interface()
+	stmt := db.Session(&gorm.Session{DryRun: true}).Limit(-1).Offset(-1).Find(dest).Statement
+	if stmt.Error != nil {
+		return 0, stmt.Error
+	}
+	where, _ := stmt.Clauses["WHERE"].Expression.(clause.Where)
+
+	var (
+		estimate int64
+		ok       bool
+		err      error
+	)
+	switch db.Dialector.Name() {
+	case "mysql":
+		if len(where.Exprs) == 0 {
+			estimate, ok, err = queryInt(stmt, "SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", stmt.Table)
+		} else {
+			estimate, ok, err = explainMySQL(stmt)
+		}
+	case "postgres":
+		if len(where.Exprs) == 0 {
+			// 从未 ANALYZE 的表 reltuples 为 -1
+			estimate, ok, err = queryInt(stmt, "SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass($1) AND reltuples >= 0", stmt.Quote(stmt.Table))
+		} else {
+			estimate, ok, err = explainPostgres(stmt)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		return estimate, nil
+	}
+
+	var count int64
+	err = db.Limit(-1).Offset(-1).Count(&count).Error
+	return count, err
+}
+
+// queryInt 在 stmt 的连接上查询一个整数, 没有结果时 ok 为 false
+func queryInt(stmt *gorm.Statement, query string, args ...any) (n int64, ok bool, err error) {
+	var v sql.NullInt64
+	err = stmt.ConnPool.QueryRowContext(stmt.Context, query, args...).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return v.Int64, err == nil && v.Valid, err
+}
+
+// explainMySQL 读 EXPLAIN 第一行的 rows * filtered / 100
+func explainMySQL(stmt *gorm.Statement) (int64, bool, error) {
+	rows, err := stmt.ConnPool.QueryContext(stmt.Context, "EXPLAIN "+stmt.SQL.String(), stmt.Vars...)
+	if err != nil {
+		return 0, false, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil || !rows.Next() {
+		return 0, false, errors.Join(err, rows.Err())
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, false, err
+	}
+
+	estimate, filtered := -1.0, 100.0
+	for i, column := range columns {
+		v, err := strconv.ParseFloat(values[i].String, 64)
+		if err != nil {
+			continue
+		}
+		switch strings.ToLower(column) {
+		case "rows":
+			estimate = v
+		case "filtered":
+			filtered = v
+		}
+	}
+	if estimate < 0 {
+		return 0, false, nil
+	}
+	return int64(estimate * filtered / 100), true, nil
+}
+
+// explainPostgres 读 EXPLAIN (FORMAT JSON) 顶层计划的 Plan Rows
+func explainPostgres(stmt *gorm.Statement) (int64, bool, error) {
+	var plan string
+	err := stmt.ConnPool.QueryRowContext(stmt.Context, "EXPLAIN (FORMAT JSON) "+stmt.SQL.String(), stmt.Vars...).Scan(&plan)
+	if err != nil {
+		return 0, false, err
+	}
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		}
+	}
+	if err := json.Unmarshal([]byte(plan), &plans); err != nil || len(plans) == 0 {
+		return 0, false, err
+	}
+	return int64(plans[0].Plan.Rows), true, nil
+}
@@ -0,0 +1,564 @@
+package ezgen
+
+import (
+	"cmp"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// FakeColumns 生成的 fake dao 告诉 FakeTable 的列信息, 与 GenParams 中的同名字段一致
+type FakeColumns struct {
+	PrimaryField string
+	SortField    string
+	Desc         bool
+	VersionField string // 乐观锁列, 可为空
+	DeletedField string // 软删除列(gorm.DeletedAt 或 soft_delete.DeletedAt), 可为空
+}
+
+// FakeTable 内存中的表, 是生成的 fake dao 的存储. 行按插入顺序保存, 读写时都会复制行(浅拷贝),
+// 调用方修改返回值不会影响表中的数据
+type FakeTable[T any] struct {
+	mu      sync.Mutex
+	columns FakeColumns
+	schema  *schema.Schema
+	rows    []*T
+}
+
+// NewFakeTable 创建空表, T 无法被 gorm 解析或 columns 中的列不存在时 panic
+func NewFakeTable[T any](columns FakeColumns) *FakeTable[T] {
+	s, err := schema.Parse(new(T), &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		panic(err)
+	}
+	for _, name := range []string{columns.PrimaryField, columns.SortField, columns.VersionField, columns.DeletedField} {
+		if name != "" && s.LookUpField(name) == nil {
+			panic(fmt.Sprintf("ezgen: fake table %s has no column %s", s.Table, name))
+		}
+	}
+	return &FakeTable[T]{columns: columns, schema: s}
+}
+
+func (t *FakeTable[T]) value(row *T, column string) any {
+	v, _ := t.schema.LookUpField(column).ValueOf(context.Background(), reflect.ValueOf(row).Elem())
+	return v
+}
+
+func (t *FakeTable[T]) set(row *T, column string, value any) error {
+	field := t.schema.LookUpField(column)
+	if v := reflect.ValueOf(value); !v.IsValid() || (v.Kind() == reflect.Pointer && v.IsNil()) {
+		// 同 SQL 的 NULL, gorm 的 Set 会忽略 nil
+		field.ReflectValueOf(context.Background(), reflect.ValueOf(row).Elem()).Set(reflect.Zero(field.FieldType))
+		return nil
+	}
+	return field.Set(context.Background(), reflect.ValueOf(row).Elem(), value)
+}
+
+func (t *FakeTable[T]) find(pk any) (int, *T) {
+	for i, row := range t.rows {
+		if CompareValues(t.value(row, t.columns.PrimaryField), pk) == 0 {
+			return i, row
+		}
+	}
+	return -1, nil
+}
+
+func (t *FakeTable[T]) deleted(row *T) bool {
+	if t.columns.DeletedField == "" {
+		return false
+	}
+	v := t.value(row, t.columns.DeletedField)
+	if d, ok := v.(gorm.DeletedAt); ok {
+		return d.Valid
+	}
+	v = valueOf(v)
+	return v != nil && !reflect.ValueOf(v).IsZero()
+}
+
+// visible 返回 row 在 mode 下是否可见
+func (t *FakeTable[T]) visible(row *T, mode DeletedMode) bool {
+	switch mode {
+	case DeletedInclude:
+		return true
+	case DeletedOnly:
+		return t.columns.DeletedField != "" && t.deleted(row)
+	}
+	return !t.deleted(row)
+}
+
+// Insert 插入 rows, 整数主键为零值时自增分配并回写到 rows 中, 同时填充自动时间和乐观锁版本
+func (t *FakeTable[T]) Insert(rows ...*T) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.insert(rows...)
+}
+
+func (t *FakeTable[T]) insert(rows ...*T) error {
+	now := time.Now()
+	pk := t.schema.LookUpField(t.columns.PrimaryField)
+	for _, row := range rows {
+		rv := reflect.ValueOf(row).Elem()
+		if _, zero := pk.ValueOf(context.Background(), rv); zero && isInteger(pk.FieldType) {
+			var id int64
+			for _, r := range t.rows {
+				id = max(id, reflect.ValueOf(t.value(r, t.columns.PrimaryField)).Convert(reflect.TypeOf(id)).Int())
+			}
+			if err := pk.Set(context.Background(), rv, id+1); err != nil {
+				return err
+			}
+		}
+		if i, _ := t.find(t.value(row, t.columns.PrimaryField)); i >= 0 {
+			return gorm.ErrDuplicatedKey
+		}
+		for _, field := range t.schema.Fields {
+			if _, zero := field.ValueOf(context.Background(), rv); zero && (field.AutoCreateTime > 0 || field.AutoUpdateTime > 0) {
+				if err := field.Set(context.Background(), rv, now); err != nil {
+					return err
+				}
+			}
+		}
+		if t.columns.VersionField != "" {
+			if err := t.set(row, t.columns.VersionField, int64(1)); err != nil {
+				return err
+			}
+		}
+		c := *row
+		t.rows = append(t.rows, &c)
+	}
+	return nil
+}
+
+// Upsert 插入 rows, 与已有行在 conflictColumns 上冲突时更新 updateColumns, updateColumns 为空时更新除主键外的所有列
+func (t *FakeTable[T]) Upsert(conflictColumns, updateColumns []string, rows ...*T) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(updateColumns) == 0 {
+		for _, field := range t.schema.Fields {
+			if field.DBName != "" && field.DBName != t.columns.PrimaryField {
+				updateColumns = append(updateColumns, field.DBName)
+			}
+		}
+	}
+	for _, row := range rows {
+		i := slices.IndexFunc(t.rows, func(r *T) bool {
+			for _, column := range conflictColumns {
+				if CompareValues(t.value(r, column), t.value(row, column)) != 0 {
+					return false
+				}
+			}
+			return len(conflictColumns) > 0
+		})
+		if i < 0 {
+			if err := t.insert(row); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, column := range updateColumns {
+			if err := t.set(t.rows[i], column, t.value(row, column)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Get 按主键读取, 不存在或在 mode 下不可见时返回 gorm.ErrRecordNotFound
+func (t *FakeTable[T]) Get(pk any, mode DeletedMode) (*T, error) {
+	if err := checkDeletedMode(mode); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, row := t.find(pk)
+	if row == nil || !t.visible(row, mode) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	c := *row
+	return &c, nil
+}
+
+// List 返回 match 的行, 排序, 软删除和分页的规则与生成的 List 相同, orderBy 不为空时按它排序.
+// total 为分页前的行数(游标分页时为游标之后的行数), 游标分页还有更多数据时返回 nextCursor
+func (t *FakeTable[T]) List(match func(row *T) bool, mode DeletedMode, pager Pager, cursor CursorPager, orderBy []Sort) (list []*T, total int64, nextCursor string, err error) {
+	if err := checkDeletedMode(mode); err != nil {
+		return nil, 0, "", err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sortField, pkField, desc := t.columns.SortField, t.columns.PrimaryField, t.columns.Desc
+	order := func(a, b *T) int {
+		c := CompareValues(t.value(a, sortField), t.value(b, sortField))
+		if c == 0 {
+			c = CompareValues(t.value(a, pkField), t.value(b, pkField))
+		}
+		if desc {
+			return -c
+		}
+		return c
+	}
+	if len(orderBy) > 0 {
+		sorts := sortsWithPK(orderBy, pkField)
+		for _, s := range sorts {
+			if t.schema.LookUpField(s.Column) == nil {
+				return nil, 0, "", fmt.Errorf("%w: unknown column %q", ErrInvalidSort, s.Column)
+			}
+		}
+		order = func(a, b *T) int {
+			for _, s := range sorts {
+				c := CompareValues(t.value(a, s.Column), t.value(b, s.Column))
+				if s.Desc {
+					c = -c
+				}
+				if c != 0 {
+					return c
+				}
+			}
+			return 0
+		}
+	}
+
+	var after *Cursor
+	useCursor := !isNil(cursor) && cursor.GetPageSize() > 0
+	if useCursor && cursor.GetCursor() != "" {
+		if after, err = DecodeCursor(cursor.GetCursor()); err != nil {
+			return nil, 0, "", err
+		}
+	}
+	for _, row := range t.rows {
+		if !t.visible(row, mode) || (match != nil && !match(row)) {
+			continue
+		}
+		if after != nil {
+			c := CompareValues(t.value(row, sortField), after.Sort)
+			if c == 0 {
+				c = CompareValues(t.value(row, pkField), after.PK)
+			}
+			if (desc && c >= 0) || (!desc && c <= 0) {
+				continue
+			}
+		}
+		c := *row
+		list = append(list, &c)
+	}
+	slices.SortStableFunc(list, order)
+	total = int64(len(list))
+
+	switch {
+	case useCursor:
+		if size := int(cursor.GetPageSize()); len(list) > size {
+			list = list[:size]
+			last := list[size-1]
+			nextCursor, err = EncodeCursor(t.value(last, sortField), t.value(last, pkField))
+		}
+	case !isNil(pager) && pager.GetPageSize() > 0 && pager.GetPageIndex() > 0:
+		offset := int((pager.GetPageIndex() - 1) * pager.GetPageSize())
+		list = list[min(offset, len(list)):min(offset+int(pager.GetPageSize()), len(list))]
+	}
+	return list, total, nextCursor, err
+}
+
+// CountBy 按 column 列分组统计 match 的行数, 结果与 ezgen.CountBy 相同按分组值升序(nil 在前)
+func (t *FakeTable[T]) CountBy(match func(row *T) bool, mode DeletedMode, column string) ([]GroupCount, error) {
+	if err := checkDeletedMode(mode); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.schema.LookUpField(column) == nil {
+		return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidField, column)
+	}
+	var counts []GroupCount
+	for _, row := range t.rows {
+		if !t.visible(row, mode) || (match != nil && !match(row)) {
+			continue
+		}
+		value := groupValue(reflect.ValueOf(t.value(row, column)))
+		i := slices.IndexFunc(counts, func(g GroupCount) bool { return CompareValues(g.Value, value) == 0 })
+		if i < 0 {
+			counts = append(counts, GroupCount{Value: value})
+			i = len(counts) - 1
+		}
+		counts[i].Count++
+	}
+	slices.SortFunc(counts, func(a, b GroupCount) int { return CompareValues(a.Value, b.Value) })
+	return counts, nil
+}
+
+// FakeCount 把 List 的精确 total 换成 strategy 的结果, pager 和 n 为 List 使用的分页参数和返回的行数.
+// CountEstimated 返回精确值
+func FakeCount(strategy CountStrategy, total int64, pager Pager, n int) int64 {
+	switch strategy {
+	case CountNone:
+		return -1
+	case CountHasMore:
+		seen := int64(n)
+		if !isNil(pager) && pager.GetPageSize() > 0 && pager.GetPageIndex() > 0 {
+			seen += int64((pager.GetPageIndex() - 1) * pager.GetPageSize())
+		}
+		if total > seen {
+			return seen + 1
+		}
+		return seen
+	}
+	return total
+}
+
+// Update 按主键更新 values(列名到值), 已软删除的行不会被更新. version 不为 nil 时只更新版本号相同的行,
+// 更新后版本号加一. 返回更新的行数
+func (t *FakeTable[T]) Update(pk any, values map[string]any, version any) (rows int64, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, row := t.find(pk)
+	if row == nil || t.deleted(row) {
+		return 0, nil
+	}
+	if version != nil && CompareValues(t.value(row, t.columns.VersionField), version) != 0 {
+		return 0, nil
+	}
+	if err = t.update(row, values); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// Restore 恢复按主键软删除的行, 同 Update 会填充自动更新时间和乐观锁版本. 没有软删除列时不做处理.
+// 返回恢复的行数
+func (t *FakeTable[T]) Restore(pk any) (rows int64, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, row := t.find(pk)
+	if row == nil || !t.deleted(row) {
+		return 0, nil
+	}
+	field := t.schema.LookUpField(t.columns.DeletedField)
+	if err = t.update(row, map[string]any{field.DBName: reflect.Zero(field.FieldType).Interface()}); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// update 把 values 写入 row, 并填充自动更新时间和乐观锁版本
+func (t *FakeTable[T]) update(row *T, values map[string]any) (err error) {
+	c := *row
+	for column, value := range values {
+		if t.schema.LookUpField(column) == nil {
+			return fmt.Errorf("ezgen: fake table %s has no column %s", t.schema.Table, column)
+		}
+		if err = t.set(&c, column, value); err != nil {
+			return err
+		}
+	}
+	for _, field := range t.schema.Fields {
+		if _, ok := values[field.DBName]; !ok && field.AutoUpdateTime > 0 {
+			if err = t.set(&c, field.DBName, time.Now()); err != nil {
+				return err
+			}
+		}
+	}
+	if t.columns.VersionField != "" {
+		var version int64
+		if v := valueOf(t.value(row, t.columns.VersionField)); v != nil {
+			version = reflect.ValueOf(v).Convert(reflect.TypeOf(version)).Int()
+		}
+		if err = t.set(&c, t.columns.VersionField, version+1); err != nil {
+			return err
+		}
+	}
+	*row = c
+	return nil
+}
+
+// Columns 返回 data 中 columns 列的值, columns 为空时返回所有非零值的列. 主键和版本列不包含在内
+func (t *FakeTable[T]) Columns(data *T, columns ...string) map[string]any {
+	values := make(map[string]any)
+	rv := reflect.ValueOf(data).Elem()
+	for _, field := range t.schema.Fields {
+		if field.DBName == "" || field.DBName == t.columns.PrimaryField || field.DBName == t.columns.VersionField {
+			continue
+		}
+		v, zero := field.ValueOf(context.Background(), rv)
+		if (len(columns) == 0 && !zero) || slices.Contains(columns, field.DBName) || slices.Contains(columns, field.Name) {
+			values[field.DBName] = v
+		}
+	}
+	return values
+}
+
+// Select 返回只保留 columns 列的行, 与 SelectScope 相同, 主键和排序列总会保留. columns 为空时返回 row
+func (t *FakeTable[T]) Select(row *T, columns ...string) *T {
+	if len(columns) == 0 {
+		return row
+	}
+	columns = slices.Concat(columns, []string{t.columns.PrimaryField, t.columns.SortField})
+	selected := new(T)
+	for _, column := range columns {
+		if err := t.set(selected, column, t.value(row, column)); err != nil {
+			panic(err)
+		}
+	}
+	return selected
+}
+
+// Delete 按主键删除, 有软删除列且 unscoped 为 false 时软删除. 返回删除的行数
+func (t *FakeTable[T]) Delete(pk any, unscoped bool) (rows int64, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	i, row := t.find(pk)
+	if row == nil || (!unscoped && t.deleted(row)) {
+		return 0, nil
+	}
+	if unscoped || t.columns.DeletedField == "" {
+		t.rows = slices.Delete(t.rows, i, i+1)
+		return 1, nil
+	}
+	field := t.schema.LookUpField(t.columns.DeletedField)
+	var marker any = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	if field.IndirectFieldType != reflect.TypeOf(gorm.DeletedAt{}) {
+		// soft_delete.DeletedAt, flag 模式为 1, 否则为删除时间
+		marker = time.Now().Unix()
+		if strings.EqualFold(field.TagSettings["SOFTDELETE"], "flag") {
+			marker = 1
+		}
+	}
+	return 1, field.Set(context.Background(), reflect.ValueOf(row).Elem(), marker)
+}
+
+// CompareValues 比较两个同类型的列值, 支持数字, 字符串, 布尔, time.Time, driver.Valuer 及其指针, nil 最小
+func CompareValues(a, b any) int {
+	a, b = valueOf(a), valueOf(b)
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case isInteger(va.Type()) && isInteger(vb.Type()):
+		if va.CanInt() && vb.CanInt() {
+			return cmp.Compare(va.Int(), vb.Int())
+		}
+		if va.CanUint() && vb.CanUint() {
+			return cmp.Compare(va.Uint(), vb.Uint())
+		}
+		return cmp.Compare(toFloat(va), toFloat(vb))
+	case va.CanFloat() || vb.CanFloat():
+		return cmp.Compare(toFloat(va), toFloat(vb))
+	case va.Kind() == reflect.String && vb.Kind() == reflect.String:
+		return strings.Compare(va.String(), vb.String())
+	case va.Kind() == reflect.Bool && vb.Kind() == reflect.Bool:
+		return cmp.Compare(boolInt(va.Bool()), boolInt(vb.Bool()))
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// Like 模拟 "column like %s%" 的匹配, 不区分大小写
+func Like(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// HasPrefix 模拟 "column like s%" 的匹配, 不区分大小写
+func HasPrefix(s, prefix string) bool {
+	return strings.HasPrefix(strings.ToLower(s), strings.ToLower(prefix))
+}
+
+// In 模拟 "column in ?", nil 不匹配
+func In[T any](v any, list []T) bool {
+	if valueOf(v) == nil {
+		return false
+	}
+	for _, e := range list {
+		if CompareValues(v, e) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// InRange 模拟 "column >= min and column <= max", min 或 max 为 nil 时不限制该端, 指定了任意一端时 nil 不匹配
+func InRange[T any](v any, min, max *T) bool {
+	if min == nil && max == nil {
+		return true
+	}
+	if valueOf(v) == nil {
+		return false
+	}
+	return (min == nil || CompareValues(v, *min) >= 0) && (max == nil || CompareValues(v, *max) <= 0)
+}
+
+// InTimeRange 模拟 "? <= column and column <= ?", v 为 time.Time 或 *time.Time, nil 不匹配
+func InTimeRange(v any, r TimeRange) bool {
+	v = valueOf(v)
+	t, ok := v.(time.Time)
+	return ok && !t.Before(r.Start) && !t.After(r.End)
+}
+
+// valueOf 解引用指针并取出 driver.Valuer 的值, nil 指针返回 nil
+func valueOf(v any) any {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	v = rv.Interface()
+	if _, ok := v.(time.Time); ok {
+		return v
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return nil
+		}
+		return value
+	}
+	return v
+}
+
+func isInteger(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch {
+	case v.CanInt():
+		return float64(v.Int())
+	case v.CanUint():
+		return float64(v.Uint())
+	case v.CanFloat():
+		return v.Float()
+	}
+	return 0
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
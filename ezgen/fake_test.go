@@ -0,0 +1,145 @@
+package ezgen
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestCompareValues(t *testing.T) {
+	one, two := int32(1), int32(2)
+	var nilInt *int32
+	early := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+	tests := []struct {
+		name string
+		a, b any
+		want int
+	}{
+		{name: "ints", a: int64(1), b: int64(2), want: -1},
+		{name: "mixed ints", a: int8(3), b: int64(3), want: 0},
+		{name: "uints", a: uint64(5), b: uint8(4), want: 1},
+		{name: "int and float", a: 2, b: 1.5, want: 1},
+		{name: "strings", a: "b", b: "a", want: 1},
+		{name: "bools", a: false, b: true, want: -1},
+		{name: "pointers", a: &one, b: &two, want: -1},
+		{name: "pointer and value", a: &two, b: int32(2), want: 0},
+		{name: "nil pointer is smallest", a: nilInt, b: int32(-5), want: -1},
+		{name: "both nil", a: nil, b: nilInt, want: 0},
+		{name: "times", a: late, b: &early, want: 1},
+		{name: "valuer", a: sql.NullInt64{Int64: 3, Valid: true}, b: int64(3), want: 0},
+		{name: "null valuer", a: sql.NullString{}, b: "", want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompareValues(tt.a, tt.b); got != tt.want {
+				t.Errorf("CompareValues(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLike(t *testing.T) {
+	tests := []struct {
+		s, pattern      string
+		like, hasPrefix bool
+	}{
+		{s: "Alice", pattern: "lic", like: true},
+		{s: "Alice", pattern: "ALI", like: true, hasPrefix: true},
+		{s: "Alice", pattern: "", like: true, hasPrefix: true},
+		{s: "Alice", pattern: "bob"},
+		// the SQL filters escape wildcards, so they match literally here too
+		{s: "Alice", pattern: "%"},
+		{s: "a_b", pattern: "_", like: true},
+		{s: "100%", pattern: "100%", like: true, hasPrefix: true},
+	}
+	for _, tt := range tests {
+		if got := Like(tt.s, tt.pattern); got != tt.like {
+			t.Errorf("Like(%q, %q) = %v, want %v", tt.s, tt.pattern, got, tt.like)
+		}
+		if got := HasPrefix(tt.s, tt.pattern); got != tt.hasPrefix {
+			t.Errorf("HasPrefix(%q, %q) = %v, want %v", tt.s, tt.pattern, got, tt.hasPrefix)
+		}
+	}
+}
+
+func TestIn(t *testing.T) {
+	three := int8(3)
+	var nilInt *int8
+	tests := []struct {
+		name string
+		v    any
+		list []int8
+		want bool
+	}{
+		{name: "member", v: int8(2), list: []int8{1, 2}, want: true},
+		{name: "pointer member", v: &three, list: []int8{3}, want: true},
+		{name: "not member", v: int8(4), list: []int8{1, 2}},
+		{name: "nil never matches", v: nilInt, list: []int8{0}},
+		{name: "empty list", v: int8(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := In(tt.v, tt.list); got != tt.want {
+				t.Errorf("In(%v, %v) = %v, want %v", tt.v, tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInRange(t *testing.T) {
+	lo, hi := int32(10), int32(20)
+	var nilInt *int32
+	tests := []struct {
+		name     string
+		v        any
+		min, max *int32
+		want     bool
+	}{
+		{name: "no bounds", v: nilInt, want: true},
+		{name: "inside", v: int32(15), min: &lo, max: &hi, want: true},
+		{name: "inclusive min", v: int32(10), min: &lo, max: &hi, want: true},
+		{name: "inclusive max", v: int32(20), min: &lo, max: &hi, want: true},
+		{name: "below", v: int32(9), min: &lo, max: &hi},
+		{name: "above", v: int32(21), min: &lo, max: &hi},
+		{name: "open max", v: int32(1000), min: &lo, want: true},
+		{name: "open min", v: int32(-1000), max: &hi, want: true},
+		{name: "nil with a bound", v: nilInt, min: &lo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InRange(tt.v, tt.min, tt.max); got != tt.want {
+				t.Errorf("InRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInTimeRange(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := TimeRange{Start: start, End: start.Add(24 * time.Hour)}
+	var nilTime *time.Time
+	tests := []struct {
+		name string
+		v    any
+		want bool
+	}{
+		{name: "start", v: start, want: true},
+		{name: "end", v: r.End, want: true},
+		{name: "pointer inside", v: ptr(start.Add(time.Hour)), want: true},
+		{name: "before", v: start.Add(-time.Second)},
+		{name: "after", v: r.End.Add(time.Second)},
+		{name: "nil", v: nilTime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InTimeRange(tt.v, r); got != tt.want {
+				t.Errorf("InTimeRange(%v) = %v, want %v", tt.v, got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
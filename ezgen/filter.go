@@ -0,0 +1,146 @@
+package ezgen
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// FilterKind List 参数中一列的过滤方式
+type FilterKind string
+
+const (
+	FilterEq     FilterKind = "eq"     // X T, column = ?, 可空列为 X *T
+	FilterLike   FilterKind = "like"   // X string, column like %x%, 同时有 eq 时为 XLike. x 中的 %, _ 按字面匹配
+	FilterPrefix FilterKind = "prefix" // XPrefix string, column like x%, x 中的 %, _ 按字面匹配
+	FilterIn     FilterKind = "in"     // XIn []T, column in ?
+	FilterRange  FilterKind = "range"  // XMin, XMax *T 闭区间, 时间列为 XRange ezgen.TimeRange
+)
+
+var filterKinds = []FilterKind{FilterEq, FilterLike, FilterPrefix, FilterIn, FilterRange}
+
+// ParseFilterKinds 解析逗号分隔的过滤方式, 如 "in,range"
+func ParseFilterKinds(s string) ([]FilterKind, error) {
+	var kinds []FilterKind
+	for _, v := range strings.Split(s, ",") {
+		kind := FilterKind(strings.TrimSpace(v))
+		if !slices.Contains(filterKinds, kind) {
+			return nil, fmt.Errorf("unknown filter %q, want one of %v", kind, filterKinds)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+// Filter 一列的过滤方式生成的 List 参数字段, 查询条件和 fake dao 的过滤表达式.
+// Conds 与 Keys 一一对应, 是参数字段生效(生成查询条件)的 go 条件
+type Filter struct {
+	Keys   []string
+	Scopes []string
+	Fakes  []string
+	Conds  []string
+}
+
+// DefaultFilterKind 未指定过滤方式时列的默认过滤方式: 时间列 range, 非唯一的字符串列 like, 其他 eq
+func DefaultFilterKind(colGoType string, unique bool) FilterKind {
+	switch {
+	case strings.Contains(colGoType, "time.Time"):
+		return FilterRange
+	case colGoType == "string" && !unique:
+		return FilterLike
+	default:
+		return FilterEq
+	}
+}
+
+// BuildFilter 生成一列的 kinds 过滤方式, 默认过滤方式生成的代码与 BuildParamsKey, BuildScope 和 BuildNullable 相同
+func BuildFilter(kinds []FilterKind, colGo, columnName, colGoType string) (*Filter, error) {
+	f := &Filter{}
+	nullable := strings.HasPrefix(colGoType, "*")
+	baseType := strings.TrimPrefix(colGoType, "*")
+	isTime := strings.Contains(baseType, "time.Time")
+	row := "row." + colGo
+	if nullable {
+		row = "*" + row
+	}
+	for i, kind := range kinds {
+		if slices.Contains(kinds[:i], kind) {
+			return nil, fmt.Errorf("duplicated filter %s", kind)
+		}
+		switch kind {
+		case FilterEq:
+			if nullable {
+				f.add(fmt.Sprintf("%s %s // optional", colGo, colGoType),
+					BuildNullable(colGo, columnName),
+					BuildFakeNullable(colGo),
+					fmt.Sprintf(`params.%s != nil`, colGo))
+			} else {
+				f.add(fmt.Sprintf("%s %s // optional", colGo, colGoType),
+					fmt.Sprintf(`Scopes(ezgen.Cond(!reflect.ValueOf(params.%s).IsZero(), "%s = ?", params.%s)).`, colGo, columnName, colGo),
+					fmt.Sprintf(`(reflect.ValueOf(params.%s).IsZero() || ezgen.CompareValues(row.%s, params.%s) == 0)`, colGo, colGo, colGo),
+					fmt.Sprintf(`!reflect.ValueOf(params.%s).IsZero()`, colGo))
+			}
+		case FilterLike, FilterPrefix:
+			if baseType != "string" {
+				return nil, fmt.Errorf("filter %s needs a string column, got %s", kind, colGoType)
+			}
+			field, pattern, match, comment := colGo, `"%%"+ezgen.EscapeLike(params.%s)+"%%"`, "Like", "optional, likely"
+			if kind == FilterPrefix {
+				field, pattern, match, comment = colGo+"Prefix", `ezgen.EscapeLike(params.%s)+"%%"`, "HasPrefix", "optional"
+			} else if slices.Contains(kinds, FilterEq) {
+				field = colGo + "Like"
+			}
+			fake := fmt.Sprintf(`ezgen.%s(%s, params.%s)`, match, row, field)
+			if nullable {
+				fake = fmt.Sprintf(`row.%s != nil && %s`, colGo, fake)
+			}
+			f.add(fmt.Sprintf("%s string // %s", field, comment),
+				fmt.Sprintf(`Scopes(ezgen.CondLike(!reflect.ValueOf(params.%s).IsZero(), "%s", `+pattern+`)).`, field, columnName, field),
+				fmt.Sprintf(`(reflect.ValueOf(params.%s).IsZero() || %s)`, field, fake),
+				fmt.Sprintf(`!reflect.ValueOf(params.%s).IsZero()`, field))
+		case FilterIn:
+			f.add(fmt.Sprintf("%sIn []%s // optional", colGo, baseType),
+				fmt.Sprintf(`Scopes(ezgen.Cond(len(params.%sIn) > 0, "%s in ?", params.%sIn)).`, colGo, columnName, colGo),
+				fmt.Sprintf(`(len(params.%sIn) == 0 || ezgen.In(row.%s, params.%sIn))`, colGo, colGo, colGo),
+				fmt.Sprintf(`len(params.%sIn) > 0`, colGo))
+		case FilterRange:
+			if isTime {
+				f.add(fmt.Sprintf("%sRange ezgen.TimeRange // optional", colGo),
+					BuildScope(colGo, columnName, baseType, false),
+					BuildFakeFilter(colGo, baseType, false),
+					fmt.Sprintf(`!reflect.ValueOf(params.%sRange).IsZero()`, colGo))
+				continue
+			}
+			if !isNumber(baseType) {
+				return nil, fmt.Errorf("filter %s needs a numeric or time column, got %s", kind, colGoType)
+			}
+			f.add(fmt.Sprintf("%sMin *%s // optional, inclusive", colGo, baseType),
+				fmt.Sprintf(`Scopes(ezgen.Nullable(params.%sMin != nil, "%s >= ?", func() any { return *params.%sMin })).`, colGo, columnName, colGo),
+				fmt.Sprintf(`ezgen.InRange(row.%s, params.%sMin, params.%sMax)`, colGo, colGo, colGo),
+				fmt.Sprintf(`params.%sMin != nil`, colGo))
+			f.Keys = append(f.Keys, fmt.Sprintf("%sMax *%s // optional, inclusive", colGo, baseType))
+			f.Scopes = append(f.Scopes, fmt.Sprintf(`Scopes(ezgen.Nullable(params.%sMax != nil, "%s <= ?", func() any { return *params.%sMax })).`, colGo, columnName, colGo))
+			f.Conds = append(f.Conds, fmt.Sprintf(`params.%sMax != nil`, colGo))
+		default:
+			return nil, fmt.Errorf("unknown filter %q", kind)
+		}
+	}
+	return f, nil
+}
+
+func (f *Filter) add(key, scope, fake, cond string) {
+	f.Keys = append(f.Keys, key)
+	f.Scopes = append(f.Scopes, scope)
+	f.Fakes = append(f.Fakes, fake)
+	f.Conds = append(f.Conds, cond)
+}
+
+func isNumber(goType string) bool {
+	switch goType {
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package ezgen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestBuildFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		kinds     []FilterKind
+		colGoType string
+		want      *Filter
+		err       string
+	}{
+		{
+			name:      "eq",
+			kinds:     []FilterKind{FilterEq},
+			colGoType: "int8",
+			want: &Filter{
+				Keys:   []string{"Status int8 // optional"},
+				Scopes: []string{`Scopes(ezgen.Cond(!reflect.ValueOf(params.Status).IsZero(), "status = ?", params.Status)).`},
+				Fakes:  []string{`(reflect.ValueOf(params.Status).IsZero() || ezgen.CompareValues(row.Status, params.Status) == 0)`},
+				Conds:  []string{`!reflect.ValueOf(params.Status).IsZero()`},
+			},
+		},
+		{
+			name:      "nullable eq",
+			kinds:     []FilterKind{FilterEq},
+			colGoType: "*int8",
+			want: &Filter{
+				Keys:   []string{"Status *int8 // optional"},
+				Scopes: []string{`Scopes(ezgen.Nullable(params.Status != nil, "status = ?", func() any { return *params.Status })).`},
+				Fakes:  []string{`(params.Status == nil || ezgen.CompareValues(row.Status, params.Status) == 0)`},
+				Conds:  []string{`params.Status != nil`},
+			},
+		},
+		{
+			name:      "in",
+			kinds:     []FilterKind{FilterIn},
+			colGoType: "*int8",
+			want: &Filter{
+				Keys:   []string{"StatusIn []int8 // optional"},
+				Scopes: []string{`Scopes(ezgen.Cond(len(params.StatusIn) > 0, "status in ?", params.StatusIn)).`},
+				Fakes:  []string{`(len(params.StatusIn) == 0 || ezgen.In(row.Status, params.StatusIn))`},
+				Conds:  []string{`len(params.StatusIn) > 0`},
+			},
+		},
+		{
+			name:      "range",
+			kinds:     []FilterKind{FilterRange},
+			colGoType: "int8",
+			want: &Filter{
+				Keys: []string{"StatusMin *int8 // optional, inclusive", "StatusMax *int8 // optional, inclusive"},
+				Scopes: []string{
+					`Scopes(ezgen.Nullable(params.StatusMin != nil, "status >= ?", func() any { return *params.StatusMin })).`,
+					`Scopes(ezgen.Nullable(params.StatusMax != nil, "status <= ?", func() any { return *params.StatusMax })).`,
+				},
+				Fakes: []string{`ezgen.InRange(row.Status, params.StatusMin, params.StatusMax)`},
+				Conds: []string{`params.StatusMin != nil`, `params.StatusMax != nil`},
+			},
+		},
+		{name: "like on a number", kinds: []FilterKind{FilterLike}, colGoType: "int8", err: "needs a string column"},
+		{name: "range on a string", kinds: []FilterKind{FilterRange}, colGoType: "string", err: "needs a numeric or time column"},
+		{name: "duplicated", kinds: []FilterKind{FilterEq, FilterEq}, colGoType: "int8", err: "duplicated filter"},
+		{name: "unknown", kinds: []FilterKind{"between"}, colGoType: "int8", err: "unknown filter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildFilter(tt.kinds, "Status", "status", tt.colGoType)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("BuildFilter() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildFilter() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildFilter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildFilterLike(t *testing.T) {
+	tests := []struct {
+		name      string
+		kinds     []FilterKind
+		colGoType string
+		wantKeys  []string
+		wantScope []string
+		wantFakes []string
+	}{
+		{
+			name:      "like",
+			kinds:     []FilterKind{FilterLike},
+			colGoType: "string",
+			wantKeys:  []string{"Name string // optional, likely"},
+			wantScope: []string{`Scopes(ezgen.CondLike(!reflect.ValueOf(params.Name).IsZero(), "name", "%"+ezgen.EscapeLike(params.Name)+"%")).`},
+			wantFakes: []string{`(reflect.ValueOf(params.Name).IsZero() || ezgen.Like(row.Name, params.Name))`},
+		},
+		{
+			name:      "eq and like",
+			kinds:     []FilterKind{FilterEq, FilterLike},
+			colGoType: "string",
+			wantKeys:  []string{"Name string // optional", "NameLike string // optional, likely"},
+		},
+		{
+			name:      "nullable prefix",
+			kinds:     []FilterKind{FilterPrefix},
+			colGoType: "*string",
+			wantKeys:  []string{"NamePrefix string // optional"},
+			wantScope: []string{`Scopes(ezgen.CondLike(!reflect.ValueOf(params.NamePrefix).IsZero(), "name", ezgen.EscapeLike(params.NamePrefix)+"%")).`},
+			wantFakes: []string{`(reflect.ValueOf(params.NamePrefix).IsZero() || row.Name != nil && ezgen.HasPrefix(*row.Name, params.NamePrefix))`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildFilter(tt.kinds, "Name", "name", tt.colGoType)
+			if err != nil {
+				t.Fatalf("BuildFilter() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Keys, tt.wantKeys) {
+				t.Errorf("Keys = %q, want %q", got.Keys, tt.wantKeys)
+			}
+			if tt.wantScope != nil && !reflect.DeepEqual(got.Scopes, tt.wantScope) {
+				t.Errorf("Scopes = %q, want %q", got.Scopes, tt.wantScope)
+			}
+			if tt.wantFakes != nil && !reflect.DeepEqual(got.Fakes, tt.wantFakes) {
+				t.Errorf("Fakes = %q, want %q", got.Fakes, tt.wantFakes)
+			}
+		})
+	}
+}
+
+func TestBuildFilterDefaultMatchesBuildScope(t *testing.T) {
+	tests := []struct {
+		colGoType string
+		unique    bool
+	}{
+		{colGoType: "string"},
+		{colGoType: "string", unique: true},
+		{colGoType: "int64"},
+		{colGoType: "time.Time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.colGoType, func(t *testing.T) {
+			f, err := BuildFilter([]FilterKind{DefaultFilterKind(tt.colGoType, tt.unique)}, "Name", "name", tt.colGoType)
+			if err != nil {
+				t.Fatalf("BuildFilter() error = %v", err)
+			}
+			if want := BuildScope("Name", "name", tt.colGoType, tt.unique); f.Scopes[0] != want {
+				t.Errorf("Scopes[0] = %s, want %s", f.Scopes[0], want)
+			}
+			if want := BuildParamsKey("Name", tt.colGoType, tt.unique); f.Keys[0] != want {
+				t.Errorf("Keys[0] = %s, want %s", f.Keys[0], want)
+			}
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := map[string]string{
+		"bob":      "bob",
+		"%":        `\%`,
+		"a_b":      `a\_b`,
+		`c:\dir`:   `c:\\dir`,
+		`50%_\off`: `50\%\_\\off`,
+	}
+	for in, want := range tests {
+		if got := EscapeLike(in); got != want {
+			t.Errorf("EscapeLike(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCondLike(t *testing.T) {
+	type user struct {
+		ID   int64
+		Name string
+	}
+	tests := []struct {
+		name      string
+		dialector gorm.Dialector
+		want      string
+	}{
+		{
+			name:      "mysql",
+			dialector: mysql.New(mysql.Config{DSN: "u:p@tcp(127.0.0.1:1)/db", SkipInitializeWithVersion: true}),
+			want:      "SELECT * FROM `users` WHERE name like '%50\\%%' escape '\\\\'",
+		},
+		{
+			name:      "postgres",
+			dialector: postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}),
+			want:      `SELECT * FROM "users" WHERE name like '%50\%%' escape '\'`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := gorm.Open(tt.dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+				return tx.Scopes(CondLike(true, "name", "%"+EscapeLike("50%")+"%")).Find(&[]user{})
+			})
+			if got != tt.want {
+				t.Errorf("CondLike() SQL = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,9 +5,13 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
 	"os"
 	"path/filepath"
-	"runtime/debug"
+	"slices"
 	"strings"
 	"text/template"
 
@@ -16,18 +20,36 @@ import (
 )
 
 type GenParams struct {
-	ModelPackage   string   // model package
+	Layout                  // package paths, resolved from the nearest go.mod of the target dir by default
+	ModelPackage   string   // module path, for custom templates. Empty when WithLayout sets every path and there is no go.mod
 	DaoName        string   // dao name
+	InterfaceName  string   // custom methods in the interface file: i{{Model}}Dao
+	CrudInterface  string   // generated methods in the .crud.go file: i{{Model}}CrudDao
 	ModelName      string   // model name
 	S              string   // the first letter(lower case) of simple Name (receiver)
 	PKType         string   // primary key type
 	ParamsKey      []string // params key
 	ParamsScopes   []string // params scopes
+	FakeFilters    []string // go expressions of the fake dao, one per params scope
+	FilterConds    []string // go conditions, one per params field, true when the filter is set
 	ImportPkgPaths []string
 	PrimaryField   string
 	PrimaryGoField string
-	Desc           bool   // params key sort
-	SortField      string // sort field, default is primary key
+	Desc           bool     // params key sort
+	SortField      string   // sort field, default is primary key
+	SortGoField    string   // go field of sort field, used to build the next cursor
+	SortFields     []string // columns List params OrderBy may sort by
+	Columns        []string // all columns, Fields of Get and List are validated against them
+	Relations      []string // associations of the model, nested ones as "Orders.Items", see WithRelations
+
+	VersionField    string   // optimistic lock column, empty if the model has none
+	VersionGoField  string   // go field of VersionField
+	DeletedField    string   // soft delete column (deleted_at or is_deleted), empty if the model has none
+	DeletedGoField  string   // go field of DeletedField
+	SensitiveFields []string // columns annotated with @ezgen:sensitive, they get no List filter
+	ConflictFields  []string // default conflict target of Upsert: primary key, or the columns of WithConflictColumns
+	UniqueFields    []string // unique columns, other conflict targets Upsert callers may pass
+	UpsertBatchSize int      // default rows per batch of Upsert, see WithUpsertBatchSize
 }
 
 //go:embed crud.dao.tpl
@@ -39,6 +61,12 @@ var interfaceTemplate string
 //go:embed dao.tpl
 var daoTemplate string
 
+//go:embed mock.dao.tpl
+var mockTemplate string
+
+//go:embed fake.dao.tpl
+var fakeTemplate string
+
 func BuildParamsKey(colGo, colGoType string, unique bool) string {
 	if colGoType == "string" && !unique {
 		return fmt.Sprintf("%s %s // optional, likely", colGo, colGoType)
@@ -51,7 +79,7 @@ func BuildParamsKey(colGo, colGoType string, unique bool) string {
 
 func BuildScope(colGo, columnName, colGoType string, unique bool) string {
 	if colGoType == `string` && !unique {
-		return fmt.Sprintf(`Scopes(ezgen.Cond(!reflect.ValueOf(params.%s).IsZero(), "%s like ?", "%%"+params.%s+"%%")).`, colGo, columnName, colGo)
+		return fmt.Sprintf(`Scopes(ezgen.CondLike(!reflect.ValueOf(params.%s).IsZero(), "%s", "%%"+ezgen.EscapeLike(params.%s)+"%%")).`, colGo, columnName, colGo)
 	} else if strings.Contains(colGoType, "time.Time") {
 		return fmt.Sprintf(`Scopes(ezgen.Cond(!reflect.ValueOf(params.%sRange).IsZero(), "? <= %s and %s <= ?",params.%sRange.Start, params.%sRange.End)).`, colGo, columnName, columnName, colGo, colGo)
 	} else {
@@ -63,236 +91,369 @@ func BuildNullable(colGo, columnName string) string {
 	return fmt.Sprintf(`Scopes(ezgen.Nullable(params.%s != nil, "%s = ?", func() any { return *params.%s })).`, colGo, columnName, colGo)
 }
 
-func Generate(params *GenParams, targetDir, entityName string) (err error) {
-	crudFileName := filepath.Join(targetDir, entityName+".crud.go")
-	interfaceFileName := filepath.Join(targetDir, entityName+".go")
-	err = generateCrud(params, crudFileName)
-	if err != nil {
-		return err
-	}
-	err = generateInterface(params, interfaceFileName)
-	if err != nil {
-		return err
+// BuildFakeFilter 与 BuildScope 相同规则的 go 表达式, row 匹配 params 时为 true
+func BuildFakeFilter(colGo, colGoType string, unique bool) string {
+	if colGoType == `string` && !unique {
+		return fmt.Sprintf(`(reflect.ValueOf(params.%s).IsZero() || ezgen.Like(row.%s, params.%s))`, colGo, colGo, colGo)
+	} else if strings.Contains(colGoType, "time.Time") {
+		return fmt.Sprintf(`(reflect.ValueOf(params.%sRange).IsZero() || ezgen.InTimeRange(row.%s, params.%sRange))`, colGo, colGo, colGo)
+	} else {
+		return fmt.Sprintf(`(reflect.ValueOf(params.%s).IsZero() || ezgen.CompareValues(row.%s, params.%s) == 0)`, colGo, colGo, colGo)
 	}
-	return
 }
 
-func generateCrud(params *GenParams, fileName string) error {
-	// 创建一个 buffer 用于存储生成的代码
-	var buf bytes.Buffer
-	// 解析和执行模板
-	tmpl, err := template.New("crud").Parse(crudTemplate)
+// BuildFakeNullable 与 BuildNullable 相同规则的 go 表达式
+func BuildFakeNullable(colGo string) string {
+	return fmt.Sprintf(`(params.%s == nil || ezgen.CompareValues(row.%s, params.%s) == 0)`, colGo, colGo, colGo)
+}
+
+func Generate(params *GenParams, targetDir, entityName string, opts ...GenOption) (err error) {
+	o := newGenOptions(opts)
+	params.naming()
+	err = resolveLayout(&params.Layout, &params.ModelPackage, o.layout, targetDir)
 	if err != nil {
 		return err
 	}
-
-	err = tmpl.Execute(&buf, params)
+	crudFileName := filepath.Join(targetDir, entityName+".crud.go")
+	interfaceFileName := filepath.Join(targetDir, entityName+".go")
+	fakeFileName := filepath.Join(targetDir, entityName+".fake.go")
+	existing, err := parseInterface(params, interfaceFileName)
 	if err != nil {
 		return err
 	}
-
-	// 使用 go/format 包格式化代码
-	formattedSource, err := imports.Process(fileName, buf.Bytes(), nil)
+	err = generateCrud(params, crudFileName, o)
 	if err != nil {
 		return err
 	}
-
-	// 生成的代码
-	file, err := os.Create(fileName)
+	err = generateFake(params, fakeFileName, o)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		_ = file.Close()
-	}()
-	_, err = file.Write(formattedSource)
+	err = generateInterface(params, existing, interfaceFileName, o)
 	if err != nil {
 		return err
 	}
+	return
+}
 
-	return nil
+// naming 设置 dao 结构体和接口的名称, 它们不导出, 对外使用 I{{Model}}Dao 和 New{{Model}}Dao
+func (p *GenParams) naming() {
+	p.DaoName = daoName(p.ModelName)
+	p.InterfaceName = "i" + p.ModelName + "Dao"
+	p.CrudInterface = "i" + p.ModelName + "CrudDao"
 }
 
-func generateInterface(params *GenParams, fileName string) error {
-	// 不覆盖已存在的文件
-	if fileExists(fileName) {
-		return nil
-	}
+func daoName(modelName string) string {
+	return unCapitalize(modelName) + "Dao"
+}
 
-	// 创建一个 buffer 用于存储生成的代码
-	var buf bytes.Buffer
-	// 解析和执行模板
-	tmpl, err := template.New("dao").Parse(interfaceTemplate)
+func generateCrud(params *GenParams, fileName string, o *genOptions) error {
+	formattedSource, err := render(CrudTemplateName, o.templates.Crud, params, fileName)
 	if err != nil {
 		return err
 	}
 
-	err = tmpl.Execute(&buf, params)
+	return o.write(fileName, formattedSource)
+}
+
+func generateFake(params *GenParams, fileName string, o *genOptions) error {
+	formattedSource, err := render(FakeTemplateName, o.templates.Fake, params, fileName)
 	if err != nil {
 		return err
 	}
 
-	// 使用 go/format 包格式化代码
-	formattedSource, err := imports.Process(fileName, buf.Bytes(), nil)
+	return o.write(fileName, formattedSource)
+}
+
+// interfaceFile 已存在的接口文件和其中声明的 dao 接口
+type interfaceFile struct {
+	fset *token.FileSet
+	src  []byte
+	decl *ast.InterfaceType
+}
+
+// parseInterface 解析已存在的接口文件, 文件不存在时返回 nil. dao 接口通常是 i{{Model}}Dao,
+// 文件中声明的是 I{{Model}}Dao 时 params.InterfaceName 随之修改, .crud.go 文件不再声明同名的别名
+func parseInterface(params *GenParams, fileName string) (*interfaceFile, error) {
+	src, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	decls := make(map[string]*ast.InterfaceType)
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			if it, ok := spec.(*ast.TypeSpec).Type.(*ast.InterfaceType); ok {
+				decls[spec.(*ast.TypeSpec).Name.Name] = it
+			}
+		}
+	}
+	for _, name := range []string{params.InterfaceName, "I" + params.ModelName + "Dao"} {
+		if decl, ok := decls[name]; ok {
+			params.InterfaceName = name
+			return &interfaceFile{fset: fset, src: src, decl: decl}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s declares neither %s nor I%sDao", fileName, params.InterfaceName, params.ModelName)
+}
 
-	// 生成的代码
-	file, err := os.Create(fileName)
+func generateInterface(params *GenParams, existing *interfaceFile, fileName string, o *genOptions) error {
+	// 不覆盖已存在的文件, 只补上内嵌的生成方法
+	if existing != nil {
+		return upgradeInterface(params, existing, fileName, o)
+	}
+
+	formattedSource, err := render(InterfaceTemplateName, o.templates.Interface, params, fileName)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		_ = file.Close()
-	}()
-	_, err = file.Write(formattedSource)
+
+	return o.write(fileName, formattedSource)
+}
+
+// upgradeInterface 为早期版本生成的 dao 接口内嵌 {{CrudInterface}}, 使新生成的方法加入接口,
+// 文件的其余内容保持不变. 接口中已列出的生成方法签名相同, 可以与内嵌的共存
+func upgradeInterface(params *GenParams, f *interfaceFile, fileName string, o *genOptions) error {
+	for _, field := range f.decl.Methods.List {
+		if ident, ok := field.Type.(*ast.Ident); ok && ident.Name == params.CrudInterface {
+			o.report.Add(fileName, FileSkipped)
+			return nil
+		}
+	}
+	offset := f.fset.Position(f.decl.Methods.Opening).Offset + 1
+	src := slices.Concat(f.src[:offset],
+		[]byte("\n\t// generated methods, kept up to date in the .crud.go file\n\t"+params.CrudInterface+"\n"),
+		f.src[offset:])
+	src, err := format.Source(src)
 	if err != nil {
 		return err
 	}
-
-	return nil
+	return o.write(fileName, src)
 }
 
-func GenerateDao(modelStructNames []string, fileName string) error {
+func GenerateDao(modelStructNames []string, fileName string, opts ...GenOption) error {
+	o := newGenOptions(opts)
 	daoNames := make([]string, 0, len(modelStructNames))
 	modelNames := make([]string, 0, len(modelStructNames))
 	for _, modelStructName := range modelStructNames {
-		daoNames = append(daoNames, unCapitalize(modelStructName)+"Dao")
+		daoNames = append(daoNames, daoName(modelStructName))
 		modelNames = append(modelNames, modelStructName)
 	}
 	type genParams struct {
+		Layout
 		ModelPackage  string
 		DaoNameList   []string // dao names
 		ModelNameList []string // model names
 	}
 
-	pkgName, _ := getModuleName()
-
 	params := &genParams{
-		ModelPackage:  pkgName,
 		DaoNameList:   daoNames,
 		ModelNameList: modelNames,
 	}
-	// 创建一个 buffer 用于存储生成的代码
-	var buf bytes.Buffer
-
-	// 解析和执行模板
-	tmpl, err := template.New("daoInit").Parse(daoTemplate)
+	err := resolveLayout(&params.Layout, &params.ModelPackage, o.layout, filepath.Dir(fileName))
 	if err != nil {
 		return err
 	}
 
-	err = tmpl.Execute(&buf, params)
+	formattedSource, err := render(DaoTemplateName, o.templates.Dao, params, fileName)
 	if err != nil {
 		return err
 	}
 
-	// 使用 go/format 包格式化代码
-	formattedSource, err := imports.Process(fileName, buf.Bytes(), nil)
+	err = o.write(fileName, formattedSource)
 	if err != nil {
 		return err
 	}
+	// mock 依赖各个 dao 的接口文件, 需要在它们生成之后
+	return generateMock(modelNames, filepath.Dir(fileName), params.Layout, o)
+}
 
-	// 生成的代码
-	file, err := os.Create(fileName)
+// render 执行模板并格式化生成的代码
+func render(name, text string, data any, fileName string) ([]byte, error) {
+	// 创建一个 buffer 用于存储生成的代码
+	var buf bytes.Buffer
+	// 解析和执行模板
+	tmpl, err := template.New(name).Funcs(FuncMap).Parse(text)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer func() {
-		_ = file.Close()
-	}()
-	_, err = file.Write(formattedSource)
+
+	err = tmpl.Execute(&buf, data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	// 使用 go/format 包格式化代码
+	return imports.Process(fileName, buf.Bytes(), nil)
+}
+
+// addFilter 添加一列的 List 参数字段和过滤条件
+func (p *GenParams) addFilter(kinds []FilterKind, colGo, columnName, colGoType string) error {
+	f, err := BuildFilter(kinds, colGo, columnName, colGoType)
+	if err != nil {
+		return err
+	}
+	p.ParamsKey = append(p.ParamsKey, f.Keys...)
+	p.ParamsScopes = append(p.ParamsScopes, f.Scopes...)
+	p.FakeFilters = append(p.FakeFilters, f.Fakes...)
+	p.FilterConds = append(p.FilterConds, f.Conds...)
 	return nil
 }
 
 func BuildParams(table, modelStructName string, columnTypes []gorm.ColumnType,
-	dataMap map[string]func(gorm.ColumnType) (dataType string)) (*GenParams, error) {
-	goModel, err := getModuleName()
+	dataMap map[string]func(gorm.ColumnType) (dataType string), opts ...ParamsOption) (*GenParams, error) {
+	o := newParamsOptions(opts)
+	tableAnnotation, err := ParseTableAnnotation(o.tableComment)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("table %s: %w", table, err)
+	}
+	if tableAnnotation.Skip {
+		return nil, fmt.Errorf("table %s: %w", table, ErrTableSkipped)
 	}
-
 	p := &GenParams{
-		ModelPackage:   goModel,
-		DaoName:        unCapitalize(modelStructName) + "Dao",
+		DaoName:        daoName(modelStructName),
 		ModelName:      modelStructName,
 		S:              "dao",
 		PKType:         "",
 		ParamsKey:      make([]string, 0),
 		ParamsScopes:   make([]string, 0),
+		FakeFilters:    make([]string, 0),
+		FilterConds:    make([]string, 0),
 		ImportPkgPaths: nil,
 		PrimaryField:   "id",
 		PrimaryGoField: "ID",
 		Desc:           true,
 		SortField:      "id",
+		SortGoField:    "ID",
+
+		UpsertBatchSize: DefaultUpsertBatchSize,
+	}
+	if tableAnnotation.Sort != "" {
+		p.Desc = tableAnnotation.Sort == "desc"
 	}
-	sortField := ""
+	sortField, sortGoField := "", ""
 	for _, columnType := range columnTypes {
 		columnName := columnType.Name()
 		colGo := SnakeToPascalCase(columnName)
-		colGoType := dataMap[strings.ToLower(columnType.DatabaseTypeName())](columnType)
+		typeOf, ok := dataMap[strings.ToLower(columnType.DatabaseTypeName())]
+		if !ok {
+			return nil, fmt.Errorf("table %s column %s: unsupported type %s", table, columnName, columnType.DatabaseTypeName())
+		}
+		colGoType := typeOf(columnType)
 		unique := false
+		p.Columns = append(p.Columns, columnName)
+
+		comment, _ := columnType.Comment()
+		annotation, err := ParseColumnAnnotation(comment)
+		if err != nil {
+			return nil, fmt.Errorf("table %s column %s: %w", table, columnName, err)
+		}
+		// WithFilters 优先于注释
+		kinds, explicit := o.filters[columnName]
+		noFilter := !explicit && (annotation.NoFilter || annotation.Sensitive)
+		if !explicit && annotation.Filters != nil {
+			kinds, explicit = annotation.Filters, true
+		}
+		if annotation.Sensitive {
+			p.SensitiveFields = append(p.SensitiveFields, columnName)
+		}
+		if annotation.Sort != "" {
+			if sortField != "" {
+				return nil, fmt.Errorf("table %s: both %s and %s are annotated with sort", table, sortField, columnName)
+			}
+			// List cursors encode the sort value, and the keyset predicate would skip NULL rows
+			if nullable, ok := columnType.Nullable(); ok && nullable {
+				return nil, fmt.Errorf("table %s column %s: nullable column can not be the sort column", table, columnName)
+			}
+			sortField, sortGoField = columnName, colGo
+			p.Desc = annotation.Sort == "desc"
+		}
 
 		if isPrimaryKey, ok := columnType.PrimaryKey(); ok && isPrimaryKey {
 			p.PrimaryField = columnName
 			p.PKType = colGoType
 			p.PrimaryGoField = colGo
-			continue
-		}
-
-		if comment, ok := columnType.Comment(); ok {
-			if strings.Contains(comment, "asc") {
-				sortField = columnName
-				p.Desc = false
-			} else if strings.Contains(comment, "desc") {
-				sortField = columnName
-				p.Desc = true
+			p.SortFields = append(p.SortFields, columnName)
+			if explicit {
+				if err := p.addFilter(kinds, colGo, columnName, colGoType); err != nil {
+					return nil, fmt.Errorf("table %s column %s: %w", table, columnName, err)
+				}
 			}
+			continue
 		}
 
 		if flag, ok := columnType.Unique(); ok {
 			unique = flag
 		}
+		if unique {
+			p.UniqueFields = append(p.UniqueFields, columnName)
+		}
 
-		if columnName == "version" || columnName == "deleted_at" || columnName == "is_deleted" {
+		if columnName == "version" {
+			p.VersionField, p.VersionGoField = columnName, colGo
+		}
+		if (columnName == "deleted_at" || columnName == "is_deleted") && p.DeletedField == "" {
+			p.DeletedField, p.DeletedGoField = columnName, colGo
+		}
+		if columnName == "version" || columnName == "deleted_at" || columnName == "is_deleted" || noFilter {
 			continue
 		}
-		p.ParamsKey = append(p.ParamsKey, BuildParamsKey(colGo, colGoType, unique))
-		if strings.Contains(colGoType, "time.Time") {
-			p.ParamsScopes = append(p.ParamsScopes, BuildScope(colGo, columnName, colGoType, unique))
-		} else if strings.HasPrefix(colGoType, "*") {
-			p.ParamsScopes = append(p.ParamsScopes, BuildNullable(colGo, columnName))
-		} else {
-			p.ParamsScopes = append(p.ParamsScopes, BuildScope(colGo, columnName, colGoType, unique))
+		p.SortFields = append(p.SortFields, columnName)
+		if !explicit {
+			kinds = []FilterKind{DefaultFilterKind(colGoType, unique)}
+		}
+		if err := p.addFilter(kinds, colGo, columnName, colGoType); err != nil {
+			return nil, fmt.Errorf("table %s column %s: %w", table, columnName, err)
+		}
+	}
+	p.Relations = o.relations
+	if o.sortable != nil {
+		for _, column := range o.sortable {
+			if !slices.Contains(p.Columns, column) {
+				return nil, fmt.Errorf("table %s: sortable column %s does not exist", table, column)
+			}
 		}
+		p.SortFields = o.sortable
 	}
 	if sortField == "" {
 		p.SortField = p.PrimaryField
+		p.SortGoField = p.PrimaryGoField
 	} else {
 		p.SortField = sortField
+		p.SortGoField = sortGoField
 	}
 
 	if p.PKType == "" {
-		err = errors.New(fmt.Sprintf("table %s no primary key", table))
-		return nil, err
+		return nil, errors.New(fmt.Sprintf("table %s no primary key", table))
+	}
+	// 多个唯一键合在一起不对应任何约束, PostgreSQL 和 SQLite 会拒绝这样的冲突列
+	p.ConflictFields = []string{p.PrimaryField}
+	if o.conflictColumns != nil {
+		for _, column := range o.conflictColumns {
+			if !slices.Contains(p.Columns, column) {
+				return nil, fmt.Errorf("table %s: conflict column %s does not exist", table, column)
+			}
+		}
+		p.ConflictFields = o.conflictColumns
+	}
+	if o.upsertBatchSize > 0 {
+		p.UpsertBatchSize = o.upsertBatchSize
 	}
 
 	return p, nil
 }
 
-func getModuleName() (string, error) {
-	info, ok := debug.ReadBuildInfo()
-	if !ok {
-		return "", fmt.Errorf("failed to read build info")
-	}
-	return info.Main.Path, nil
-}
-
 func unCapitalize(s string) string {
 	if s == "" {
 		return ""
@@ -22,6 +22,24 @@ func ToQueryStructMeta(v any) *QueryStructMeta {
 	return *(**QueryStructMeta)(unsafe.Pointer(uintptr(unsafe.Pointer(&v)) + unsafe.Sizeof(uintptr(0)))) // skip eface.itab
 }
 
+// RelationNames 返回 gen 模型(ToQueryStructMeta 的结果)的关联, 包括嵌套的关联 "Orders.Items", 用于 WithRelations
+func RelationNames(meta *QueryStructMeta) []string {
+	var names []string
+	var walk func(relations []field.Relation)
+	walk = func(relations []field.Relation) {
+		for _, r := range relations {
+			names = append(names, r.Path())
+			walk(r.ChildRelations())
+		}
+	}
+	for _, f := range meta.Fields {
+		if f.Relation != nil {
+			walk([]field.Relation{*f.Relation})
+		}
+	}
+	return names
+}
+
 // QueryStructMeta struct info in generated code
 type QueryStructMeta struct {
 	db *gorm.DB
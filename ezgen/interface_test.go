@@ -0,0 +1,74 @@
+package ezgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpgradeInterface(t *testing.T) {
+	tests := []struct {
+		name, src, wantName string
+		wantAction          FileAction
+	}{
+		{
+			name:       "up to date",
+			src:        "package dao\n\ntype iUserDao interface {\n\tiUserCrudDao\n\n\tCustom() error\n}\n",
+			wantName:   "iUserDao",
+			wantAction: FileSkipped,
+		},
+		{
+			name:       "without the generated methods",
+			src:        "package dao\n\ntype iUserDao interface {\n\tCustom() error\n}\n",
+			wantName:   "iUserDao",
+			wantAction: FileOverwritten,
+		},
+		{
+			name:       "exported interface",
+			src:        "package dao\n\ntype IUserDao interface {\n\tCustom() error\n}\n",
+			wantName:   "IUserDao",
+			wantAction: FileOverwritten,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileName := filepath.Join(t.TempDir(), "user.go")
+			if err := os.WriteFile(fileName, []byte(tt.src), 0o666); err != nil {
+				t.Fatal(err)
+			}
+			params := &GenParams{ModelName: "User"}
+			params.naming()
+			f, err := parseInterface(params, fileName)
+			if err != nil {
+				t.Fatalf("parseInterface() error = %v", err)
+			}
+			if params.InterfaceName != tt.wantName {
+				t.Errorf("InterfaceName = %q, want %q", params.InterfaceName, tt.wantName)
+			}
+			report := &Report{}
+			if err = generateInterface(params, f, fileName, newGenOptions([]GenOption{WithReport(report)})); err != nil {
+				t.Fatalf("generateInterface() error = %v", err)
+			}
+			if files := report.Files(); len(files) != 1 || files[0].Action != tt.wantAction {
+				t.Errorf("report = %+v, want %s", files, tt.wantAction)
+			}
+			src, _ := os.ReadFile(fileName)
+			if !strings.Contains(string(src), "\tiUserCrudDao\n") || !strings.Contains(string(src), "Custom() error") {
+				t.Errorf("interface file:\n%s", src)
+			}
+		})
+	}
+
+	t.Run("no interface", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "user.go")
+		if err := os.WriteFile(fileName, []byte("package dao\n"), 0o666); err != nil {
+			t.Fatal(err)
+		}
+		params := &GenParams{ModelName: "User"}
+		params.naming()
+		if _, err := parseInterface(params, fileName); err == nil {
+			t.Error("parseInterface() error = nil, want an error")
+		}
+	})
+}
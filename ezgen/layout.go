@@ -0,0 +1,120 @@
+package ezgen
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Layout 生成代码引用的各个包的导入路径和 dao 包名
+type Layout struct {
+	ModelPkgPath string // model 包导入路径, 默认为 dao 包下的 model
+	QueryPkgPath string // query 包导入路径, 默认为 dao 包下的 query
+	DaoPkgPath   string // dao 包导入路径, 默认为生成目录对应的导入路径
+	DaoPkgName   string // dao 包名, 默认为 dao 包导入路径的最后一段
+}
+
+// WithLayout 指定生成代码的包布局, 未设置的字段从生成目录所在的 go.mod 推导
+func WithLayout(l Layout) GenOption {
+	return func(o *genOptions) {
+		o.layout = l
+	}
+}
+
+// ResolveLayout 根据 dir 向上查找最近的 go.mod, 推导 dir 作为 dao 包时的默认布局
+func ResolveLayout(dir string) (modulePath string, l Layout, err error) {
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", l, err
+	}
+
+	root := dir
+	for {
+		data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			modulePath = modfile.ModulePath(data)
+			break
+		}
+		if !os.IsNotExist(err) {
+			return "", l, err
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			return "", l, fmt.Errorf("no go.mod found for %s", dir)
+		}
+		root = parent
+	}
+	if modulePath == "" {
+		return "", l, fmt.Errorf("no module path in %s", filepath.Join(root, "go.mod"))
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return "", l, err
+	}
+	daoPkgPath := path.Join(modulePath, filepath.ToSlash(rel))
+	l = Layout{
+		ModelPkgPath: daoPkgPath + "/model",
+		QueryPkgPath: daoPkgPath + "/query",
+		DaoPkgPath:   daoPkgPath,
+		DaoPkgName:   pkgName(daoPkgPath),
+	}
+	return modulePath, l, nil
+}
+
+// merge 用 def 补全 l 中未设置的字段
+func (l *Layout) merge(def Layout) {
+	if l.ModelPkgPath == "" {
+		l.ModelPkgPath = def.ModelPkgPath
+	}
+	if l.QueryPkgPath == "" {
+		l.QueryPkgPath = def.QueryPkgPath
+	}
+	if l.DaoPkgPath == "" {
+		l.DaoPkgPath = def.DaoPkgPath
+	}
+	if l.DaoPkgName == "" {
+		l.DaoPkgName = def.DaoPkgName
+	}
+}
+
+func (l *Layout) complete() bool {
+	return l.ModelPkgPath != "" && l.QueryPkgPath != "" && l.DaoPkgPath != "" && l.DaoPkgName != ""
+}
+
+// resolveLayout 按 l, opt, dir 所在 go.mod 的优先级补全布局.
+// 布局完整时 go.mod 只用于设置 modulePath, 找不到也不报错
+func resolveLayout(l *Layout, modulePath *string, opt Layout, dir string) error {
+	l.merge(opt)
+	if l.complete() && *modulePath != "" {
+		return nil
+	}
+
+	mod, def, err := ResolveLayout(dir)
+	if err != nil {
+		if l.complete() {
+			return nil
+		}
+		return err
+	}
+	l.merge(def)
+	if *modulePath == "" {
+		*modulePath = mod
+	}
+	return nil
+}
+
+// pkgName 由导入路径得到合法的包名
+func pkgName(pkgPath string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' {
+			return '_'
+		}
+		return r
+	}, path.Base(pkgPath))
+	return strings.ToLower(name)
+}
@@ -0,0 +1,60 @@
+package ezgen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveLayout(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "internal", "dao")
+	complete := Layout{ModelPkgPath: "example.com/m", QueryPkgPath: "example.com/q", DaoPkgPath: "example.com/d", DaoPkgName: "d"}
+	tests := []struct {
+		name       string
+		dir        string
+		opt        Layout
+		want       Layout
+		wantModule string
+		wantErr    bool
+	}{
+		{
+			name:       "from go.mod",
+			dir:        dir,
+			want:       Layout{ModelPkgPath: "example.com/app/internal/dao/model", QueryPkgPath: "example.com/app/internal/dao/query", DaoPkgPath: "example.com/app/internal/dao", DaoPkgName: "dao"},
+			wantModule: "example.com/app",
+		},
+		{
+			name:       "partial option",
+			dir:        dir,
+			opt:        Layout{ModelPkgPath: "example.com/m"},
+			want:       Layout{ModelPkgPath: "example.com/m", QueryPkgPath: "example.com/app/internal/dao/query", DaoPkgPath: "example.com/app/internal/dao", DaoPkgName: "dao"},
+			wantModule: "example.com/app",
+		},
+		{name: "complete option", dir: dir, opt: complete, want: complete, wantModule: "example.com/app"},
+		{name: "complete option without go.mod", dir: t.TempDir(), opt: complete, want: complete},
+		{name: "no go.mod", dir: t.TempDir(), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l Layout
+			var module string
+			err := resolveLayout(&l, &module, tt.opt, tt.dir)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("resolveLayout() = %+v, want an error", l)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveLayout() error = %v", err)
+			}
+			if l != tt.want || module != tt.wantModule {
+				t.Errorf("resolveLayout() = %+v, %q, want %+v, %q", l, module, tt.want, tt.wantModule)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package ezgen
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrOptimisticLockConflict 带版本号的更新没有命中任何行, 数据已被其他请求修改(或已不存在)
+var ErrOptimisticLockConflict = errors.New("ezgen: optimistic lock conflict")
+
+// RetryOnConflict 执行一次 读取-修改-写入 的 fn, 返回 ErrOptimisticLockConflict 时重新执行, 最多执行 attempts 次.
+// fn 每次都需要重新读取数据, 返回最后一次的错误
+func RetryOnConflict(ctx context.Context, attempts int, fn func(ctx context.Context) error) (err error) {
+	for i := 0; i < max(attempts, 1); i++ {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		err = fn(ctx)
+		if !errors.Is(err, ErrOptimisticLockConflict) {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,406 @@
+package ezgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MockParams mock.dao.tpl 的数据
+type MockParams struct {
+	Layout
+	Imports []string    // 方法签名用到的包, 已包含 dao 包
+	Mocks   []*MockType // 每个 dao 一个
+}
+
+// MockType 一个 I{{Model}}Dao 的 mock
+type MockType struct {
+	ModelName string
+	Methods   []*MockMethod // 生成的方法和自定义方法, 按名称排序
+}
+
+// MockMethod mock 的一个方法, 签名中 dao 包内的类型已加上包名
+type MockMethod struct {
+	Name        string
+	Params      string // ctx context.Context, data ...*model.User
+	Args        string // ctx, data...
+	Results     string // (result *model.User, err error)
+	ReturnsSelf bool   // 唯一的返回值是 dao 接口本身, 如 WithTx, 默认返回 mock 自己
+}
+
+const mockPkgName = "mock"
+
+// generateMock 解析 dao 目录下各个 I{{Model}}Dao 接口, 在 dao/mock 中生成对应的 mock
+func generateMock(modelNames []string, daoDir string, layout Layout, o *genOptions) error {
+	fileName := filepath.Join(daoDir, mockPkgName, mockPkgName+".go")
+	fset := token.NewFileSet()
+	interfaces := make(map[string]*ast.InterfaceType)
+	aliases := make(map[string]string)                // unexported interface name -> exported alias, as I{{Model}}Dao = i{{Model}}Dao
+	fileImports := make(map[string]map[string]string) // interface name -> import name -> import spec
+	sources, err := daoSources(daoDir, o)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 && o.dryRun {
+		// dry-run 时没有指定 Report, 首次生成时也没有可以解析的接口
+		o.report.Add(fileName, FileSkipped)
+		return nil
+	}
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		file, err := parser.ParseFile(fset, name, sources[name], parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		imports := importsOf(file)
+		ast.Inspect(file, func(n ast.Node) bool {
+			if spec, ok := n.(*ast.TypeSpec); ok {
+				switch typ := spec.Type.(type) {
+				case *ast.InterfaceType:
+					interfaces[spec.Name.Name] = typ
+					fileImports[spec.Name.Name] = imports
+				case *ast.Ident:
+					if spec.Assign.IsValid() && spec.Name.IsExported() {
+						aliases[typ.Name] = spec.Name.Name
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	params := &MockParams{Layout: layout}
+	used := map[string]string{
+		"sync":            strconv.Quote("sync"),
+		layout.DaoPkgName: importSpec(layout.DaoPkgName, layout.DaoPkgPath),
+	}
+	for _, modelName := range modelNames {
+		m := &mockBuilder{
+			fset:       fset,
+			interfaces: interfaces,
+			aliases:    aliases,
+			imports:    fileImports,
+			used:       used,
+			daoPkg:     layout.DaoPkgName,
+			self:       "I" + modelName + "Dao",
+			seen:       make(map[string]bool),
+		}
+		mock := &MockType{ModelName: modelName}
+		if _, ok := interfaces[m.resolve(m.self)]; !ok && o.dryRun {
+			o.report.Add(fileName, FileSkipped)
+			return nil
+		}
+		if err = m.collect(m.self, mock); err != nil {
+			return fmt.Errorf("mock %s: %w", modelName, err)
+		}
+		sort.Slice(mock.Methods, func(i, j int) bool { return mock.Methods[i].Name < mock.Methods[j].Name })
+		params.Mocks = append(params.Mocks, mock)
+	}
+	for _, spec := range used {
+		params.Imports = append(params.Imports, spec)
+	}
+	sort.Strings(params.Imports)
+
+	src, err := render(MockTemplateName, o.templates.Mock, params, fileName)
+	if err != nil {
+		return err
+	}
+	return o.write(fileName, src)
+}
+
+// daoSources 返回 dao 目录下的 go 源文件, dry run 时以本次渲染的内容代替磁盘上的文件
+func daoSources(daoDir string, o *genOptions) (map[string][]byte, error) {
+	sources := make(map[string][]byte)
+	entries, err := os.ReadDir(daoDir)
+	if err != nil && !(os.IsNotExist(err) && o.dryRun) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(filepath.Join(daoDir, name))
+		if err != nil {
+			return nil, err
+		}
+		sources[filepath.Join(daoDir, name)] = src
+	}
+	if o.dryRun {
+		for name, src := range o.report.renderedIn(daoDir) {
+			if strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
+				sources[name] = src
+			}
+		}
+	}
+	return sources, nil
+}
+
+type mockBuilder struct {
+	fset       *token.FileSet
+	interfaces map[string]*ast.InterfaceType
+	aliases    map[string]string
+	imports    map[string]map[string]string
+	used       map[string]string // 生成的 mock 用到的包
+	daoPkg     string
+	self       string // I{{Model}}Dao
+	seen       map[string]bool
+}
+
+// resolve 返回别名 name 指向的接口名, 如 I{{Model}}Dao 指向接口文件中的 i{{Model}}Dao
+func (m *mockBuilder) resolve(name string) string {
+	for target, alias := range m.aliases {
+		if alias == name {
+			return target
+		}
+	}
+	return name
+}
+
+// collect 收集接口 name 及其内嵌接口的方法
+func (m *mockBuilder) collect(name string, mock *MockType) error {
+	name = m.resolve(name)
+	it, ok := m.interfaces[name]
+	if !ok {
+		return fmt.Errorf("interface %s not found", name)
+	}
+	for _, field := range it.Methods.List {
+		switch typ := field.Type.(type) {
+		case *ast.FuncType:
+			for _, ident := range field.Names {
+				if m.seen[ident.Name] {
+					continue
+				}
+				m.seen[ident.Name] = true
+				method, err := m.method(ident.Name, typ, m.imports[name])
+				if err != nil {
+					return fmt.Errorf("method %s: %w", ident.Name, err)
+				}
+				mock.Methods = append(mock.Methods, method)
+			}
+		case *ast.Ident:
+			if err := m.collect(typ.Name, mock); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported embedded type %s in %s", m.print(field.Type), name)
+		}
+	}
+	return nil
+}
+
+func (m *mockBuilder) method(name string, fn *ast.FuncType, imports map[string]string) (*MockMethod, error) {
+	method := &MockMethod{Name: name}
+	var params, args []string
+	i := 0
+	for _, field := range fn.Params.List {
+		typ, err := m.qualify(field.Type, imports)
+		if err != nil {
+			return nil, err
+		}
+		names := fieldNames(field, "p", &i)
+		_, variadic := field.Type.(*ast.Ellipsis)
+		for _, n := range names {
+			params = append(params, n+" "+m.print(typ))
+			if variadic {
+				n += "..."
+			}
+			args = append(args, n)
+		}
+	}
+	method.Params = strings.Join(params, ", ")
+	method.Args = strings.Join(args, ", ")
+
+	var results []string
+	i = 0
+	if fn.Results != nil {
+		for _, field := range fn.Results.List {
+			typ, err := m.qualify(field.Type, imports)
+			if err != nil {
+				return nil, err
+			}
+			for _, n := range fieldNames(field, "r", &i) {
+				results = append(results, n+" "+m.print(typ))
+			}
+		}
+		if len(results) == 1 {
+			ident, ok := fn.Results.List[0].Type.(*ast.Ident)
+			// WithTx 返回 I{{Model}}Dao
+			method.ReturnsSelf = ok && m.resolve(ident.Name) == m.resolve(m.self)
+		}
+	}
+	if len(results) > 0 {
+		method.Results = "(" + strings.Join(results, ", ") + ")"
+	}
+	return method, nil
+}
+
+// fieldNames 返回参数名, 匿名参数和 _ 使用 prefix+序号
+func fieldNames(field *ast.Field, prefix string, i *int) []string {
+	if len(field.Names) == 0 {
+		*i++
+		return []string{prefix + strconv.Itoa(*i-1)}
+	}
+	names := make([]string, 0, len(field.Names))
+	for _, ident := range field.Names {
+		*i++
+		if ident.Name == "_" {
+			names = append(names, prefix+strconv.Itoa(*i-1))
+		} else {
+			names = append(names, ident.Name)
+		}
+	}
+	return names
+}
+
+// qualify 为 dao 包内的类型加上包名, 并记录用到的包
+func (m *mockBuilder) qualify(expr ast.Expr, imports map[string]string) (ast.Expr, error) {
+	var err error
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if types.Universe.Lookup(e.Name) != nil {
+			return e, nil
+		}
+		name := e.Name
+		if alias, ok := m.aliases[name]; ok {
+			name = alias
+		} else if !ast.IsExported(name) {
+			return nil, fmt.Errorf("unexported type %s can not be used outside the dao package", name)
+		}
+		return &ast.SelectorExpr{X: ast.NewIdent(m.daoPkg), Sel: ast.NewIdent(name)}, nil
+	case *ast.SelectorExpr:
+		if x, ok := e.X.(*ast.Ident); ok {
+			spec, ok := imports[x.Name]
+			if !ok {
+				return nil, fmt.Errorf("unknown package %s", x.Name)
+			}
+			m.used[x.Name] = spec
+		}
+		return e, nil
+	case *ast.StarExpr:
+		c := *e
+		c.X, err = m.qualify(e.X, imports)
+		return &c, err
+	case *ast.Ellipsis:
+		c := *e
+		c.Elt, err = m.qualify(e.Elt, imports)
+		return &c, err
+	case *ast.ArrayType:
+		c := *e
+		c.Elt, err = m.qualify(e.Elt, imports)
+		return &c, err
+	case *ast.MapType:
+		c := *e
+		if c.Key, err = m.qualify(e.Key, imports); err != nil {
+			return nil, err
+		}
+		c.Value, err = m.qualify(e.Value, imports)
+		return &c, err
+	case *ast.ChanType:
+		c := *e
+		c.Value, err = m.qualify(e.Value, imports)
+		return &c, err
+	case *ast.IndexExpr:
+		c := *e
+		if c.X, err = m.qualify(e.X, imports); err != nil {
+			return nil, err
+		}
+		c.Index, err = m.qualify(e.Index, imports)
+		return &c, err
+	case *ast.IndexListExpr:
+		c := *e
+		if c.X, err = m.qualify(e.X, imports); err != nil {
+			return nil, err
+		}
+		c.Indices = make([]ast.Expr, len(e.Indices))
+		for i, index := range e.Indices {
+			if c.Indices[i], err = m.qualify(index, imports); err != nil {
+				return nil, err
+			}
+		}
+		return &c, nil
+	case *ast.FuncType:
+		c := *e
+		c.Params, err = m.qualifyFields(e.Params, imports)
+		if err != nil {
+			return nil, err
+		}
+		c.Results, err = m.qualifyFields(e.Results, imports)
+		return &c, err
+	case *ast.InterfaceType:
+		if len(e.Methods.List) > 0 {
+			return nil, fmt.Errorf("unsupported inline interface %s", m.print(e))
+		}
+		return e, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", m.print(expr))
+	}
+}
+
+func (m *mockBuilder) qualifyFields(fields *ast.FieldList, imports map[string]string) (*ast.FieldList, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	c := &ast.FieldList{List: make([]*ast.Field, len(fields.List))}
+	for i, field := range fields.List {
+		typ, err := m.qualify(field.Type, imports)
+		if err != nil {
+			return nil, err
+		}
+		c.List[i] = &ast.Field{Names: field.Names, Type: typ}
+	}
+	return c, nil
+}
+
+func (m *mockBuilder) print(expr ast.Expr) string {
+	var buf bytes.Buffer
+	_ = printer.Fprint(&buf, m.fset, expr)
+	return buf.String()
+}
+
+// importsOf 返回文件中导入的包名到 import 声明的映射
+func importsOf(file *ast.File) map[string]string {
+	imports := make(map[string]string, len(file.Imports))
+	for _, spec := range file.Imports {
+		pkgPath, _ := strconv.Unquote(spec.Path.Value)
+		name := importName(pkgPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = importSpec(name, pkgPath)
+	}
+	return imports
+}
+
+// importName 推测未命名导入的包名, 去掉 /vN, .vN 后缀和 go- 前缀
+func importName(pkgPath string) string {
+	name := path.Base(pkgPath)
+	if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = path.Base(path.Dir(pkgPath))
+	}
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	return strings.ReplaceAll(strings.TrimPrefix(name, "go-"), "-", "_")
+}
+
+func importSpec(name, pkgPath string) string {
+	if name == path.Base(pkgPath) {
+		return strconv.Quote(pkgPath)
+	}
+	return name + " " + strconv.Quote(pkgPath)
+}
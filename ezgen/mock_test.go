@@ -0,0 +1,94 @@
+package ezgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const mockTestCrud = `package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"example.com/app/dao/model"
+)
+
+type iUserCrudDao interface {
+	Get(ctx context.Context, id int64, opts ...GetOption) (result *model.User, err error)
+	WithTx(tx *gorm.DB) IUserDao
+}
+
+type IUserDao = iUserDao
+`
+
+const mockTestInterface = `package dao
+
+import (
+	"context"
+
+	m "example.com/app/dao/model"
+)
+
+type iUserDao interface {
+	iUserCrudDao
+
+	Get(ctx context.Context, id int64, opts ...GetOption) (result *m.User, err error)
+	Rename(_ context.Context, user *m.User, names map[int64]string) error
+}
+`
+
+func TestGenerateMock(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "user.go"), []byte(mockTestInterface), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	report := &Report{}
+	o := newGenOptions([]GenOption{WithDryRun(), WithReport(report)})
+	// the crud file only exists as a dry-run rendering
+	report.render(filepath.Join(dir, "user.crud.go"), []byte(mockTestCrud))
+	layout := Layout{DaoPkgName: "dao", DaoPkgPath: "example.com/app/dao", ModelPkgPath: "example.com/app/dao/model"}
+	if err := generateMock([]string{"User"}, dir, layout, o); err != nil {
+		t.Fatalf("generateMock() error = %v", err)
+	}
+
+	files := report.Files()
+	if len(files) != 1 || files[0].Action != FileCreated {
+		t.Fatalf("report = %+v, want one created mock", files)
+	}
+	src := string(report.renderedIn(filepath.Join(dir, mockPkgName))[filepath.Join(dir, mockPkgName, "mock.go")])
+	for _, want := range []string{
+		"GetFunc    func(ctx context.Context, id int64, opts ...dao.GetOption) (result *model.User, err error)",
+		"RenameFunc func(p0 context.Context, user *m.User, names map[int64]string) (r0 error)",
+		"WithTxFunc func(tx *gorm.DB) (r0 dao.IUserDao)",
+		`m "example.com/app/dao/model"`,
+		"var _ dao.IUserDao = (*UserDao)(nil)",
+		"return _m\n", // WithTx returns the mock itself by default
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("mock does not contain %q:\n%s", want, src)
+		}
+	}
+	if strings.Count(src, "func (_m *UserDao) Get(") != 1 {
+		t.Errorf("Get should be mocked once, it is declared by both interfaces:\n%s", src)
+	}
+}
+
+func TestGenerateMockUnexportedType(t *testing.T) {
+	dir := t.TempDir()
+	src := strings.Replace(mockTestInterface, "names map[int64]string", "names nameMap", 1)
+	if err := os.WriteFile(filepath.Join(dir, "user.go"), []byte(src), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "user.crud.go"), []byte(mockTestCrud), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	o := newGenOptions([]GenOption{WithDryRun()})
+	err := generateMock([]string{"User"}, dir, Layout{DaoPkgName: "dao", DaoPkgPath: "example.com/app/dao"}, o)
+	if err == nil || !strings.Contains(err.Error(), "unexported type nameMap") {
+		t.Fatalf("generateMock() error = %v, want unexported type error", err)
+	}
+}
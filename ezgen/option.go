@@ -0,0 +1,227 @@
+package ezgen
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// GenOption 用于调整 Generate / GenerateDao 的行为
+type GenOption func(*genOptions)
+
+type genOptions struct {
+	report    *Report
+	dryRun    bool
+	templates *TemplateSet
+	layout    Layout
+}
+
+func newGenOptions(opts []GenOption) *genOptions {
+	o := &genOptions{templates: DefaultTemplates()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithReport 将生成过程中每个文件的处理结果记录到 r
+func WithReport(r *Report) GenOption {
+	return func(o *genOptions) {
+		o.report = r
+	}
+}
+
+// WithDryRun 只在内存中渲染模板, 不写文件, 与现有文件的差异记录在 Report 中.
+// Generate 和 GenerateDao 需要共用同一个 Report, mock 才能基于本次渲染的接口生成
+func WithDryRun() GenOption {
+	return func(o *genOptions) {
+		o.dryRun = true
+	}
+}
+
+// write 写入生成的代码, 内容未变化时不重写文件
+func (o *genOptions) write(fileName string, src []byte) error {
+	old, err := os.ReadFile(fileName)
+	exists := err == nil
+	if exists && bytes.Equal(old, src) {
+		o.report.add(FileResult{Path: fileName, Action: FileUnchanged})
+		return nil
+	}
+
+	result := FileResult{Path: fileName, Action: FileCreated}
+	if exists {
+		result.Action = FileOverwritten
+	}
+	if o.dryRun {
+		oldName := fileName
+		if !exists {
+			oldName = "/dev/null"
+		}
+		result.Diff = UnifiedDiff(oldName, fileName, old, src)
+		o.report.add(result)
+		o.report.render(fileName, src)
+		return nil
+	}
+
+	// 生成的代码
+	if err = os.MkdirAll(filepath.Dir(fileName), 0o777); err != nil {
+		return err
+	}
+	if err = os.WriteFile(fileName, src, 0o666); err != nil {
+		return err
+	}
+	o.report.add(result)
+	return nil
+}
+
+// FileAction 表示生成时对目标文件做的处理
+type FileAction string
+
+const (
+	FileCreated     FileAction = "created"
+	FileOverwritten FileAction = "overwritten"
+	FileUnchanged   FileAction = "unchanged"
+	FileSkipped     FileAction = "skipped"
+)
+
+// FileResult 单个文件的生成结果
+type FileResult struct {
+	Path   string
+	Action FileAction
+	Diff   string // dry run 时与现有文件的 unified diff
+}
+
+// Report 汇总一次生成过程中所有文件的处理结果, 可并发使用
+type Report struct {
+	mu       sync.Mutex
+	files    []FileResult
+	rendered map[string][]byte // dry run 时渲染但未写入的文件内容, 供后续生成的 mock 解析
+}
+
+// Add 记录一个文件结果, 调用方也可以用它汇总 gorm gen 等外部生成的文件
+func (r *Report) Add(path string, action FileAction) {
+	r.add(FileResult{Path: path, Action: action})
+}
+
+func (r *Report) add(result FileResult) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.files = append(r.files, result)
+}
+
+// render 记录 dry run 渲染的文件内容
+func (r *Report) render(path string, src []byte) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.rendered == nil {
+		r.rendered = make(map[string][]byte)
+	}
+	r.rendered[path] = src
+}
+
+// renderedIn 返回 dry run 渲染到目录 dir 下(不含子目录)的文件内容
+func (r *Report) renderedIn(dir string) map[string][]byte {
+	files := make(map[string][]byte)
+	if r == nil {
+		return files
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for path, src := range r.rendered {
+		if filepath.Dir(path) == filepath.Clean(dir) {
+			files[path] = src
+		}
+	}
+	return files
+}
+
+// Changed 是否有文件被(或 dry run 时将被)创建或覆盖
+func (r *Report) Changed() bool {
+	for _, f := range r.Files() {
+		if f.Action == FileCreated || f.Action == FileOverwritten {
+			return true
+		}
+	}
+	return false
+}
+
+// Files 返回已记录的文件结果
+func (r *Report) Files() []FileResult {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]FileResult(nil), r.files...)
+}
+
+// ParamsOption 用于调整 BuildParams 的行为
+type ParamsOption func(*paramsOptions)
+
+type paramsOptions struct {
+	filters         map[string][]FilterKind
+	tableComment    string
+	sortable        []string
+	relations       []string
+	conflictColumns []string
+	upsertBatchSize int
+}
+
+func newParamsOptions(opts []ParamsOption) *paramsOptions {
+	o := &paramsOptions{filters: make(map[string][]FilterKind)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithFilters 指定列 column 的过滤方式, 覆盖默认过滤方式和列注释中的 @ezgen:filter, nofilter 和 sensitive.
+// 主键默认不生成过滤条件, 指定后才生成
+func WithFilters(column string, kinds ...FilterKind) ParamsOption {
+	return func(o *paramsOptions) {
+		o.filters[column] = kinds
+	}
+}
+
+// WithTableComment 传入表注释, BuildParams 解析其中的 ezgen 指令, 见 ParseTableAnnotation
+func WithTableComment(comment string) ParamsOption {
+	return func(o *paramsOptions) {
+		o.tableComment = comment
+	}
+}
+
+// WithSortable 指定 List 参数 OrderBy 可以排序的列, 默认为除乐观锁, 软删除和 nofilter, sensitive 列外的所有列
+func WithSortable(columns ...string) ParamsOption {
+	return func(o *paramsOptions) {
+		o.sortable = columns
+	}
+}
+
+// WithRelations 传入模型的关联(可以是嵌套路径 "Orders.Items"), 生成对应的常量用于 Preload, 见 RelationNames
+func WithRelations(names ...string) ParamsOption {
+	return func(o *paramsOptions) {
+		o.relations = names
+	}
+}
+
+// WithConflictColumns 指定生成的 Upsert 默认的冲突列, 需要是主键或一个唯一索引的全部列, 默认为主键
+func WithConflictColumns(columns ...string) ParamsOption {
+	return func(o *paramsOptions) {
+		o.conflictColumns = columns
+	}
+}
+
+// WithUpsertBatchSize 指定生成的 Upsert 每批写入的行数, 默认为 DefaultUpsertBatchSize.
+// 调用时可以通过 ContextWithBatchSize 覆盖
+func WithUpsertBatchSize(size int) ParamsOption {
+	return func(o *paramsOptions) {
+		o.upsertBatchSize = size
+	}
+}
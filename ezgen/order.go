@@ -0,0 +1,84 @@
+package ezgen
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidSort = errors.New("ezgen: invalid sort")
+
+// Sort List 的一个排序键
+type Sort struct {
+	Column string // 列名, 必须在生成的 {{Model}}SortColumns 中
+	Desc   bool
+}
+
+// ParseSorts 解析逗号分隔的排序键, "-" 前缀表示降序, 如 "-created_at,name"
+func ParseSorts(s string) ([]Sort, error) {
+	var sorts []Sort
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		column, desc := strings.CutPrefix(v, "-")
+		if column == "" {
+			return nil, fmt.Errorf("%w: empty column in %q", ErrInvalidSort, s)
+		}
+		sorts = append(sorts, Sort{Column: column, Desc: desc})
+	}
+	return sorts, nil
+}
+
+// ValidateSorts 检查 sorts 中的列都在 allowed 中且没有重复
+func ValidateSorts(sorts []Sort, allowed []string) error {
+	for i, s := range sorts {
+		if !slices.Contains(allowed, s.Column) {
+			return fmt.Errorf("%w: column %q is not sortable", ErrInvalidSort, s.Column)
+		}
+		if slices.ContainsFunc(sorts[:i], func(prev Sort) bool { return prev.Column == s.Column }) {
+			return fmt.Errorf("%w: duplicated column %q", ErrInvalidSort, s.Column)
+		}
+	}
+	return nil
+}
+
+// OrderScope 按 sorts 排序, sorts 为空时按默认排序 def 排序, 并以主键作为最后的排序键(方向与最后一个排序键相同).
+// 列不在 allowed 中时查询返回 ErrInvalidSort
+func OrderScope(sorts []Sort, allowed []string, def Sort, primaryField string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(sorts) == 0 {
+			sorts = []Sort{def}
+		} else if err := ValidateSorts(sorts, allowed); err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		for _, s := range sortsWithPK(sorts, primaryField) {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: s.Column}, Desc: s.Desc})
+		}
+		return db
+	}
+}
+
+// sortsWithPK 在 sorts 后追加主键, sorts 中已有主键时不追加
+func sortsWithPK(sorts []Sort, primaryField string) []Sort {
+	if slices.ContainsFunc(sorts, func(s Sort) bool { return s.Column == primaryField }) {
+		return sorts
+	}
+	return append(slices.Clip(sorts), Sort{Column: primaryField, Desc: sorts[len(sorts)-1].Desc})
+}
+
+// orderedBy 判断 db 的 ORDER BY 中是否已有列 column
+func orderedBy(db *gorm.DB, column string) bool {
+	c, ok := db.Statement.Clauses["ORDER BY"]
+	if !ok {
+		return false
+	}
+	orderBy, ok := c.Expression.(clause.OrderBy)
+	return ok && slices.ContainsFunc(orderBy.Columns, func(c clause.OrderByColumn) bool { return c.Column.Name == column })
+}
@@ -0,0 +1,68 @@
+package ezgen
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSorts(t *testing.T) {
+	got, err := ParseSorts(" -created_at, name,,")
+	if err != nil {
+		t.Fatalf("ParseSorts() error = %v", err)
+	}
+	want := []Sort{{Column: "created_at", Desc: true}, {Column: "name"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSorts() = %+v, want %+v", got, want)
+	}
+	if _, err = ParseSorts("name,-"); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("ParseSorts() error = %v, want ErrInvalidSort", err)
+	}
+}
+
+func TestOrderScope(t *testing.T) {
+	allowed := []string{"id", "name", "created_at"}
+	tests := []struct {
+		name   string
+		sorts  []Sort
+		def    Sort
+		cursor bool
+		want   string
+		err    error
+	}{
+		{name: "default", def: Sort{Column: "created_at", Desc: true}, want: "ORDER BY `created_at` DESC,`id` DESC"},
+		{name: "default primary key", def: Sort{Column: "id"}, want: "ORDER BY `id` LIMIT"},
+		{name: "sorts", sorts: []Sort{{Column: "name"}, {Column: "created_at", Desc: true}}, def: Sort{Column: "id"},
+			want: "ORDER BY `name`,`created_at` DESC,`id` DESC"},
+		{name: "sorts with primary key", sorts: []Sort{{Column: "id", Desc: true}, {Column: "name"}}, def: Sort{Column: "id"},
+			want: "ORDER BY `id` DESC,`name` LIMIT"},
+		{name: "cursor", def: Sort{Column: "created_at"}, cursor: true, want: "ORDER BY `created_at`,`id` LIMIT"},
+		{name: "not allowed", sorts: []Sort{{Column: "password"}}, def: Sort{Column: "id"}, err: ErrInvalidSort},
+		{name: "duplicated", sorts: []Sort{{Column: "name"}, {Column: "name", Desc: true}}, def: Sort{Column: "id"}, err: ErrInvalidSort},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []struct{ ID int64 }
+			db := dryRunDB(t).Table("users").Scopes(OrderScope(tt.sorts, allowed, tt.def, "id"))
+			if tt.cursor {
+				db = db.Scopes(PaginateCursor(valuePager{size: 10}, tt.def.Column, "id", tt.def.Desc))
+			} else {
+				db = db.Limit(10)
+			}
+			db = db.Find(&rows)
+			if tt.err != nil {
+				if !errors.Is(db.Error, tt.err) {
+					t.Fatalf("error = %v, want %v", db.Error, tt.err)
+				}
+				return
+			}
+			if db.Error != nil {
+				t.Fatal(db.Error)
+			}
+			if sql := db.Statement.SQL.String(); !strings.Contains(sql, tt.want) {
+				t.Errorf("SQL = %s, want %s", sql, tt.want)
+			}
+		})
+	}
+}
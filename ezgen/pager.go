@@ -8,3 +8,12 @@ type Pager interface {
 	// GetPageIndex 分页页码, 需要大于 0
 	GetPageIndex() uint32
 }
+
+// CursorPager 表示一个基于游标(keyset)分页的参数请求
+type CursorPager interface {
+	// GetPageSize 分页大小, 需要大于 0
+	GetPageSize() uint32
+
+	// GetCursor 上一页返回的游标, 为空表示第一页
+	GetCursor() string
+}
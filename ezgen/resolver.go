@@ -0,0 +1,37 @@
+package ezgen
+
+import (
+	"gorm.io/gen"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// UseReplicas 为 db 注册 dbresolver 读写分离, 查询默认随机走 replicas, 写入和事务内的查询走 db 本身.
+// replicas 为空时不做任何处理
+func UseReplicas(db *gorm.DB, replicas ...gorm.Dialector) error {
+	if len(replicas) == 0 {
+		return nil
+	}
+	return db.Use(dbresolver.Register(dbresolver.Config{Replicas: replicas}))
+}
+
+// WithPrimary 为 true 时查询(包括 Preload)强制走主库, 用于写后立即读
+func WithPrimary(primary bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if primary {
+			return db.Clauses(dbresolver.Write)
+		} else {
+			return db
+		}
+	}
+}
+
+func WithPrimaryGen(primary bool) func(db gen.Dao) gen.Dao {
+	return func(db gen.Dao) gen.Dao {
+		if primary {
+			return db.Clauses(dbresolver.Write)
+		} else {
+			return db
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package ezgen
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidField = errors.New("ezgen: invalid field")
+
+// ValidateFields 检查 fields 中的列都在 allowed 中
+func ValidateFields(fields, allowed []string) error {
+	for _, f := range fields {
+		if !slices.Contains(allowed, f) {
+			return fmt.Errorf("%w: unknown column %q", ErrInvalidField, f)
+		}
+	}
+	return nil
+}
+
+// SelectScope 只查询 fields 中的列, required 中的列(如主键和排序列)总会被查询.
+// fields 为空时查询所有列, 列不在 allowed 中时查询返回 ErrInvalidField
+func SelectScope(fields, allowed []string, required ...string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(fields) == 0 {
+			return db
+		}
+		if err := ValidateFields(fields, allowed); err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		columns := slices.Clone(fields)
+		for _, r := range required {
+			if !slices.Contains(columns, r) {
+				columns = append(columns, r)
+			}
+		}
+		return db.Select(columns)
+	}
+}
+
+// PreloadScope 预加载 names 中的关联, names 为 nil 时预加载所有关联, 为空切片时不预加载.
+// 名称可以是嵌套路径 "Orders.Items", conds 为按名称指定的预加载条件(同 gorm 的 Preload 参数), 有条件的关联总会被预加载
+func PreloadScope(names []string, conds map[string][]any) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if names == nil {
+			db = db.Preload(clause.Associations)
+		}
+		for _, name := range names {
+			db = db.Preload(name, conds[name]...)
+		}
+		for _, name := range slices.Sorted(maps.Keys(conds)) {
+			if !slices.Contains(names, name) {
+				db = db.Preload(name, conds[name]...)
+			}
+		}
+		return db
+	}
+}
@@ -0,0 +1,132 @@
+package ezgen
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/jinzhu/inflection"
+)
+
+// 模板文件名, 自定义模板目录中使用相同的文件名覆盖内置模板
+const (
+	CrudTemplateName      = "crud.dao.tpl"
+	InterfaceTemplateName = "interface.dao.tpl"
+	DaoTemplateName       = "dao.tpl"
+	MockTemplateName      = "mock.dao.tpl"
+	FakeTemplateName      = "fake.dao.tpl"
+)
+
+// TemplateSet 生成 CRUD, interface, fake, dao 初始化文件和 mock 所用的模板
+type TemplateSet struct {
+	Crud      string // crud.dao.tpl, 数据为 *GenParams
+	Interface string // interface.dao.tpl, 数据为 *GenParams
+	Dao       string // dao.tpl
+	Mock      string // mock.dao.tpl, 数据为 *MockParams
+	Fake      string // fake.dao.tpl, 数据为 *GenParams
+}
+
+// DefaultTemplates 返回内置模板
+func DefaultTemplates() *TemplateSet {
+	return &TemplateSet{
+		Crud:      crudTemplate,
+		Interface: interfaceTemplate,
+		Dao:       daoTemplate,
+		Mock:      mockTemplate,
+		Fake:      fakeTemplate,
+	}
+}
+
+// LoadTemplates 从 fsys 的根目录读取模板, 缺失的文件使用内置模板
+func LoadTemplates(fsys fs.FS) (*TemplateSet, error) {
+	ts := DefaultTemplates()
+	for name, text := range map[string]*string{
+		CrudTemplateName:      &ts.Crud,
+		InterfaceTemplateName: &ts.Interface,
+		DaoTemplateName:       &ts.Dao,
+		MockTemplateName:      &ts.Mock,
+		FakeTemplateName:      &ts.Fake,
+	} {
+		data, err := fs.ReadFile(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		*text = string(data)
+	}
+	return ts, nil
+}
+
+// LoadTemplateDir 从目录读取模板, 缺失的文件使用内置模板
+func LoadTemplateDir(dir string) (*TemplateSet, error) {
+	return LoadTemplates(os.DirFS(dir))
+}
+
+// WithTemplates 使用自定义模板生成代码, ts 为 nil 时使用内置模板
+func WithTemplates(ts *TemplateSet) GenOption {
+	return func(o *genOptions) {
+		if ts != nil {
+			o.templates = ts
+		}
+	}
+}
+
+// FuncMap 模板中可以使用的函数:
+//
+//	pascalCase   user_id -> UserID
+//	camelCase    user_id -> userID
+//	snakeCase    UserID -> user_id
+//	plural       User -> Users
+//	singular     users -> user
+//	capitalize   user -> User
+//	uncapitalize User -> user
+//	lower, upper, join, contains, hasPrefix, hasSuffix, trimPrefix, trimSuffix, replace
+//	             同 strings 包中的同名函数, replace 会替换所有匹配
+var FuncMap = template.FuncMap{
+	"pascalCase":   SnakeToPascalCase,
+	"camelCase":    func(s string) string { return unCapitalize(SnakeToPascalCase(s)) },
+	"snakeCase":    PascalToSnakeCase,
+	"plural":       inflection.Plural,
+	"singular":     inflection.Singular,
+	"capitalize":   capitalize,
+	"uncapitalize": unCapitalize,
+	"lower":        strings.ToLower,
+	"upper":        strings.ToUpper,
+	"join":         strings.Join,
+	"contains":     strings.Contains,
+	"hasPrefix":    strings.HasPrefix,
+	"hasSuffix":    strings.HasSuffix,
+	"trimPrefix":   strings.TrimPrefix,
+	"trimSuffix":   strings.TrimSuffix,
+	"replace":      strings.ReplaceAll,
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// PascalToSnakeCase 将 PascalCase/camelCase 转换为 snake_case, 连续的大写字母视为一个单词
+func PascalToSnakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
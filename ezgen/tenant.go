@@ -0,0 +1,145 @@
+package ezgen
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoTenant context 中没有租户
+var ErrNoTenant = errors.New("ezgen: no tenant in context")
+
+const (
+	tenantsKey    = "ezgen:tenants"     // Route 返回的 db 上的 *Tenants
+	tenantNameKey = "ezgen:tenant_name" // Tenants 打开的 db 所属的租户, 用于区分缓存
+)
+
+type tenantKey struct{}
+
+// WithTenant 返回携带租户(或数据源)名称的 context, 经 Tenants.Route 的 db 按它选择数据库
+func WithTenant(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, name)
+}
+
+// TenantFrom 取出 context 中的租户名称
+func TenantFrom(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(tenantKey{}).(string)
+	return name, ok && name != ""
+}
+
+// OpenFunc 打开租户 name 的数据库
+type OpenFunc func(ctx context.Context, name string) (*gorm.DB, error)
+
+// Tenants 按租户或数据源名称管理 *gorm.DB, 首次使用时通过 OpenFunc 打开, 打开失败不会缓存
+type Tenants struct {
+	open OpenFunc
+	mu   sync.Mutex
+	dbs  map[string]*tenantDB
+}
+
+type tenantDB struct {
+	done chan struct{} // 打开完成后关闭
+	db   *gorm.DB
+	err  error
+}
+
+// NewTenants 创建 Tenants, open 为 nil 时只能使用 Set 注册的数据库
+func NewTenants(open OpenFunc) *Tenants {
+	return &Tenants{open: open, dbs: make(map[string]*tenantDB)}
+}
+
+// Set 注册已打开的数据库, 替换同名的数据库
+func (t *Tenants) Set(name string, db *gorm.DB) {
+	e := &tenantDB{done: make(chan struct{}), db: tenantSession(db, name)}
+	close(e.done)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dbs[name] = e
+}
+
+// Remove 移除租户的数据库并返回它, 由调用方决定是否关闭连接. 之后的使用会重新打开
+func (t *Tenants) Remove(name string) (*gorm.DB, bool) {
+	t.mu.Lock()
+	e, ok := t.dbs[name]
+	delete(t.dbs, name)
+	t.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	<-e.done
+	return e.db, e.err == nil
+}
+
+// DB 返回租户 name 的数据库, 并发的首次调用只打开一次
+func (t *Tenants) DB(ctx context.Context, name string) (*gorm.DB, error) {
+	t.mu.Lock()
+	e, ok := t.dbs[name]
+	if !ok {
+		e = &tenantDB{done: make(chan struct{})}
+		t.dbs[name] = e
+	}
+	t.mu.Unlock()
+
+	if !ok {
+		if t.open == nil {
+			e.err = errors.New("ezgen: unknown tenant " + name)
+		} else if e.db, e.err = t.open(ctx, name); e.err == nil {
+			e.db = tenantSession(e.db, name)
+		}
+		if e.err != nil {
+			t.mu.Lock()
+			if t.dbs[name] == e {
+				delete(t.dbs, name)
+			}
+			t.mu.Unlock()
+		}
+		close(e.done)
+	}
+
+	select {
+	case <-e.done:
+		return e.db, e.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Route 返回按 context 中的租户(见 WithTenant)选择数据库的 db, context 中没有租户时使用 db 本身.
+// 用它创建的 dao 每次请求都通过 Conn 解析数据库, 如 dao.Init(tenants.Route(db))
+func (t *Tenants) Route(db *gorm.DB) *gorm.DB {
+	return db.Set(tenantsKey, t).Session(&gorm.Session{})
+}
+
+// tenantSession 标记 db 所属的租户, 可以并发复用
+func tenantSession(db *gorm.DB, name string) *gorm.DB {
+	return db.Set(tenantNameKey, name).Session(&gorm.Session{})
+}
+
+// tenantConn 返回 ctx 中租户的数据库, db 不是 Route 返回的或 ctx 中没有租户时返回 db.
+// 打开失败时返回带有该错误的 db, 之后的操作都会返回这个错误
+func tenantConn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	t, ok := db.Get(tenantsKey)
+	if !ok {
+		return db
+	}
+	name, ok := TenantFrom(ctx)
+	if !ok {
+		return db
+	}
+	tdb, err := t.(*Tenants).DB(ctx, name)
+	if err != nil {
+		db = db.Session(&gorm.Session{})
+		_ = db.AddError(err)
+		return db
+	}
+	return tdb
+}
+
+// tenantName 返回 db 所属的租户, 不属于任何租户时为空
+func tenantName(db *gorm.DB) string {
+	name, _ := db.Get(tenantNameKey)
+	s, _ := name.(string)
+	return s
+}
@@ -0,0 +1,91 @@
+package ezgen
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoDB db 为 nil 且 context 中没有事务, 通常是生成的 dao 包还没有调用 Init 或 Use
+var ErrNoDB = errors.New("ezgen: no database, call Init or Use first")
+
+type txKey struct{}
+
+// ContextWithTx 返回携带事务 tx 的 context, 生成的 dao 方法会优先使用该事务
+func ContextWithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext 取出 context 中携带的事务
+func TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txKey{}).(*gorm.DB)
+	return tx, ok && tx != nil
+}
+
+// Conn 返回 context 中的事务, 没有事务时返回 db (Route 返回的 db 按 context 中的租户选择), 均已绑定 ctx
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	return tenantConn(ctx, db).WithContext(ctx)
+}
+
+// Transaction 在事务中执行 fn, 事务通过 fn 的 ctx 传递给生成的 dao 方法.
+// ctx 中已有事务时嵌套执行(savepoint), fn 返回错误或 panic 时回滚. db 为 nil 且 ctx 中没有事务时返回 ErrNoDB
+func Transaction(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error, opts ...*sql.TxOptions) error {
+	if _, ok := TxFromContext(ctx); !ok && db == nil {
+		return ErrNoDB
+	}
+	pending, nested := ctx.Value(txTablesKey{}).(*txTables)
+	if !nested {
+		pending = &txTables{}
+	}
+	err := Conn(ctx, db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ContextWithTx(ctx, tx), txTablesKey{}, pending))
+	}, opts...)
+	if err == nil && !nested {
+		InvalidateCache(ctx, db, pending.list()...)
+	}
+	return err
+}
+
+type txTablesKey struct{}
+
+// txTables 事务中写过的表, 提交后需要使缓存失效
+type txTables struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (t *txTables) add(tables ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, table := range tables {
+		if !slices.Contains(t.names, table) {
+			t.names = append(t.names, table)
+		}
+	}
+}
+
+func (t *txTables) list() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.names)
+}
+
+// TrackWrites 返回记录写过的表的 ctx, 用于调用方自己开启的事务 (如传给 WithTx 的): 以该 ctx 调用的 dao 写方法
+// 记录它们写过的表, 事务提交后调用 done 再使这些表的缓存失效, 同 Transaction. ctx 已在 Transaction 中时 done 不做任何处理,
+// 由外层事务提交后失效
+func TrackWrites(ctx context.Context, db *gorm.DB) (_ context.Context, done func()) {
+	if _, ok := ctx.Value(txTablesKey{}).(*txTables); ok {
+		return ctx, func() {}
+	}
+	pending := &txTables{}
+	return context.WithValue(ctx, txTablesKey{}, pending), func() {
+		InvalidateCache(ctx, db, pending.list()...)
+	}
+}
@@ -1,22 +1,142 @@
 package ezgen
 
 import (
+	"database/sql"
+	"fmt"
+	"reflect"
+
 	"gorm.io/gen"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+// CountStrategy FindAndCount 统计总数的方式
+type CountStrategy int
+
+const (
+	CountDefault   CountStrategy = iota // 未指定, 见 ListStrategy; FindAndCount 按 CountExact 处理
+	CountExact                          // 查询分页后再查询 COUNT(*), 两次查询在同一个可重复读的只读事务中执行, total 与分页一致
+	CountNone                           // 只查询分页, total 为 -1
+	CountEstimated                      // 估算 total: 没有查询条件时读表的统计信息, 否则读 EXPLAIN, 只支持 MySQL 和 PostgreSQL, 其他数据库精确统计
+	CountHasMore                        // 多查询一行判断后面是否还有数据, total 为本页及之前的行数, 还有数据时再加 1
+	CountExactNoTx                      // 同 CountExact, 但不开启事务, 两次查询之间的写入会使 total 与分页不一致. 用于不支持可重复读或只读事务的数据库
 )
 
+// Deprecated: CountExact 已经在快照中统计, 使用 CountExact
+const CountExactSnapshot = CountExact
+
+// ListStrategy 返回 List 实际使用的统计方式. 游标分页每页都要知道后面是否还有数据(NextCursor),
+// 除非明确指定了精确统计, 都使用 CountHasMore, 避免每页都执行一次 COUNT(*); 没有游标时默认为 CountExact
+func ListStrategy(strategy CountStrategy, cursor bool) CountStrategy {
+	switch {
+	case cursor && strategy != CountExact && strategy != CountExactNoTx:
+		return CountHasMore
+	case strategy == CountDefault:
+		return CountExact
+	}
+	return strategy
+}
+
+// snapshotTxOptions CountExact 开启的事务的选项, 可重复读保证两次查询读到同一个快照.
+// 不支持该隔离级别的数据库可以使用 CountExactNoTx, 或在自己开启的事务中统计
+var snapshotTxOptions = sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+
+// resolverWriteKey dbresolver.Write 在 Statement.Settings 中的 key
+const resolverWriteKey = "gorm:db_resolver:write"
+
+// FindAndCountTransaction 在只读事务中查询 result 和分页前的总数, 同 FindAndCount(db, result, CountExact)
 func FindAndCountTransaction(db *gorm.DB, result interface{}) (int64, error) {
+	return FindAndCount(db, result, CountExact)
+}
+
+// FindAndCount 查询 result 并按 strategy 统计分页前的总数.
+// CountExact 的两次查询在只读事务中执行, db 已在事务中或使用查询缓存(见 CacheMode)时不再开启事务.
+// 需要走主库时 db 上的 WithPrimary 要在调用前生效(而不是作为 Scopes), 否则事务会开在从库上
+func FindAndCount(db *gorm.DB, result interface{}, strategy CountStrategy) (int64, error) {
+	switch strategy {
+	case CountExactNoTx:
+		return findAndCount(db, result)
+	case CountDefault, CountExact:
+		var count int64
+		err := snapshot(db, func(tx *gorm.DB) (err error) {
+			count, err = findAndCount(tx, result)
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+		return count, nil
+	case CountNone:
+		return -1, db.Find(result).Error
+	case CountEstimated:
+		db = db.Model(result)
+		if err := db.Find(result).Error; err != nil {
+			return 0, err
+		}
+		return estimateCount(db, result)
+	case CountHasMore:
+		return findHasMore(db, result)
+	}
+	return 0, fmt.Errorf("ezgen: unknown count strategy %d", strategy)
+}
+
+// findAndCount 查询 result, 再用同一个 Statement 查询分页前的总数
+func findAndCount(db *gorm.DB, result interface{}) (int64, error) {
 	var count int64
+	// Find 执行过 scopes 的 Statement 由 Count 复用
+	db = db.Model(result)
 	if err := db.Find(result).Error; err != nil {
 		return 0, err
 	}
-
-	if err := db.Model(result).Limit(-1).Offset(-1).Count(&count).Error; err != nil {
+	if err := db.Limit(-1).Offset(-1).Count(&count).Error; err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
+// snapshot 在只读事务中执行 fn, 没有指定主库时事务开在从库上
+func snapshot(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok || CacheModeFrom(db.Statement.Context) != ModeNone {
+		return fn(db)
+	}
+	if _, ok := db.Statement.Settings.Load(resolverWriteKey); !ok {
+		db = db.Clauses(dbresolver.Read)
+	}
+	opts := snapshotTxOptions
+	return db.Transaction(fn, &opts)
+}
+
+// findHasMore 把 db 的 LIMIT n 改为 n+1 查询, 多出的一行不放入 result
+func findHasMore(db *gorm.DB, result interface{}) (int64, error) {
+	limit, offset := -1, 0
+	db = db.Scopes(func(tx *gorm.DB) *gorm.DB {
+		c, ok := tx.Statement.Clauses["LIMIT"].Expression.(clause.Limit)
+		if !ok {
+			return tx
+		}
+		offset = c.Offset
+		if c.Limit != nil && *c.Limit >= 0 {
+			limit = *c.Limit
+			tx = tx.Limit(limit + 1)
+		}
+		return tx
+	}).Find(result)
+	if db.Error != nil {
+		return 0, db.Error
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(result))
+	if rv.Kind() != reflect.Slice {
+		return int64(offset) + db.RowsAffected, nil
+	}
+	if n := rv.Len(); limit < 0 || n <= limit {
+		return int64(offset + n), nil
+	}
+	rv.SetLen(limit)
+	return int64(offset + limit + 1), nil
+}
+
 func FindAndCountTransactionGen(db gen.DO, result interface{}) (int64, error) {
 	err := db.Scan(result)
 	if err != nil {
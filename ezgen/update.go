@@ -0,0 +1,19 @@
+package ezgen
+
+import (
+	"errors"
+	"maps"
+)
+
+var ErrNoColumns = errors.New("ezgen: no columns to update")
+
+// SplitVersion 取出 columns 中的乐观锁版本号, 返回的 columns 不再包含版本列, 原 map 不会被修改
+func SplitVersion(columns map[string]any, versionField string) (map[string]any, any, bool) {
+	version, ok := columns[versionField]
+	if !ok {
+		return columns, nil, false
+	}
+	columns = maps.Clone(columns)
+	delete(columns, versionField)
+	return columns, version, true
+}
@@ -0,0 +1,39 @@
+package ezgen
+
+import (
+	"context"
+
+	"gorm.io/gorm/clause"
+)
+
+// DefaultUpsertBatchSize 生成的 Upsert 方法每批写入的行数, 可以通过 WithUpsertBatchSize 修改
+const DefaultUpsertBatchSize = 1000
+
+type batchSizeKey struct{}
+
+// ContextWithBatchSize 返回携带批大小的 context, 生成的 Upsert 按它分批写入, 用于调整单次大批量导入
+func ContextWithBatchSize(ctx context.Context, size int) context.Context {
+	return context.WithValue(ctx, batchSizeKey{}, size)
+}
+
+// BatchSizeFromContext 取出 context 中大于 0 的批大小, 没有时返回 def
+func BatchSizeFromContext(ctx context.Context, def int) int {
+	if size, ok := ctx.Value(batchSizeKey{}).(int); ok && size > 0 {
+		return size
+	}
+	return def
+}
+
+// OnConflict 构造 upsert 子句, updateColumns 为空时更新除主键外的所有列
+func OnConflict(conflictColumns, updateColumns []string) clause.OnConflict {
+	onConflict := clause.OnConflict{Columns: make([]clause.Column, 0, len(conflictColumns))}
+	for _, name := range conflictColumns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: name})
+	}
+	if len(updateColumns) == 0 {
+		onConflict.UpdateAll = true
+	} else {
+		onConflict.DoUpdates = clause.AssignmentColumns(updateColumns)
+	}
+	return onConflict
+}